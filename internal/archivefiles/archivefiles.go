@@ -17,6 +17,20 @@ import (
 	"github.com/goreleaser/goreleaser/v2/pkg/config"
 )
 
+// CheckZip returns an error if any of the given files has zip settings while
+// none of the given formats is zip, as they would be ignored.
+func CheckZip(files []config.File, formats ...string) error {
+	if slices.Contains(formats, "zip") {
+		return nil
+	}
+	for _, f := range files {
+		if f.Zip != (config.ZipFileInfo{}) {
+			return fmt.Errorf("%s: zip settings are only used by the zip format, got %v", f.Source, formats)
+		}
+	}
+	return nil
+}
+
 // Eval evaluates the given list of files to their final form.
 func Eval(template *tmpl.Template, files []config.File) ([]config.File, error) {
 	var result []config.File
@@ -59,6 +73,7 @@ func Eval(template *tmpl.Template, files []config.File) ([]config.File, error) {
 				Source:      filepath.ToSlash(file),
 				Destination: filepath.ToSlash(dst),
 				Info:        f.Info,
+				Zip:         f.Zip,
 			})
 		}
 	}
@@ -45,6 +45,24 @@ func TestEval(t *testing.T) {
 		}, result)
 	})
 
+	t.Run("zip settings", func(t *testing.T) {
+		result, err := Eval(tmpl, []config.File{
+			{
+				Source:      "./testdata/**/d.txt",
+				Destination: "var/foobar/",
+				Zip:         config.ZipFileInfo{CompressionMethod: "store"},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []config.File{
+			{
+				Source:      "testdata/a/b/c/d.txt",
+				Destination: "var/foobar/d.txt",
+				Zip:         config.ZipFileInfo{CompressionMethod: "store"},
+			},
+		}, result)
+	})
+
 	t.Run("templated src error", func(t *testing.T) {
 		_, err := Eval(tmpl, []config.File{
 			{
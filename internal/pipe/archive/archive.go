@@ -96,6 +96,13 @@ func (Pipe) Default(ctx *context.Context) error {
 				archive.NameTemplate = defaultBinaryNameTemplate
 			}
 		}
+		formats := slices.Clone(archive.Formats)
+		for _, over := range archive.FormatOverrides {
+			formats = append(formats, over.Formats...)
+		}
+		if err := archivefiles.CheckZip(archive.Files, formats...); err != nil {
+			return fmt.Errorf("invalid archive: %d: %w", i, err)
+		}
 		archive.BuildsInfo.Mode = 0o755
 		ids.Inc(archive.ID)
 	}
@@ -363,10 +370,23 @@ func (d EnhancedArchive) Add(f config.File) error {
 		Source:      f.Source,
 		Destination: name,
 		Info:        f.Info,
+		Zip:         f.Zip,
 	}
 	return d.a.Add(ff)
 }
 
+// AddFS adds all the files of the given file system.
+func (d EnhancedArchive) AddFS(fsys fs.FS, prefix string) error {
+	name := strings.ReplaceAll(filepath.Join(d.wrap, prefix), "\\", "/")
+	log.Debugf("adding file system as %s", name)
+	return archive.AddFS(d.a, fsys, name)
+}
+
+// Format returns the format of the underlying archive.
+func (d EnhancedArchive) Format() string {
+	return d.a.Format()
+}
+
 // Close closes the underlying archive.
 func (d EnhancedArchive) Close() error {
 	return d.a.Close()
@@ -830,6 +830,35 @@ func TestDefaultSet(t *testing.T) {
 	require.Equal(t, config.File{Source: "foo"}, ctx.Config.Archives[0].Files[0])
 }
 
+func TestDefaultZipSettings(t *testing.T) {
+	files := []config.File{{
+		Source: "foo.png",
+		Zip:    config.ZipFileInfo{CompressionMethod: "store"},
+	}}
+	ctx := testctx.NewWithCfg(config.Project{
+		Archives: []config.Archive{
+			{
+				Formats: []string{"tar.gz"},
+				Files:   files,
+			},
+		},
+	})
+	require.EqualError(t, Pipe{}.Default(ctx), "invalid archive: 0: foo.png: zip settings are only used by the zip format, got [tar.gz]")
+
+	ctx = testctx.NewWithCfg(config.Project{
+		Archives: []config.Archive{
+			{
+				Formats: []string{"tar.gz"},
+				FormatOverrides: []config.FormatOverride{
+					{Goos: "windows", Formats: []string{"zip"}},
+				},
+				Files: files,
+			},
+		},
+	})
+	require.NoError(t, Pipe{}.Default(ctx))
+}
+
 func TestDefaultMixFormats(t *testing.T) {
 	ctx := testctx.NewWithCfg(config.Project{
 		Archives: []config.Archive{
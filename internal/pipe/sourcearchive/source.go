@@ -132,5 +132,5 @@ func (Pipe) Default(ctx *context.Context) error {
 	if archive.NameTemplate == "" {
 		archive.NameTemplate = "{{ .ProjectName }}-{{ .Version }}"
 	}
-	return nil
+	return archivefiles.CheckZip(archive.Files, archive.Format)
 }
@@ -173,6 +173,20 @@ func TestDefault(t *testing.T) {
 	}, ctx.Config.Source)
 }
 
+func TestDefaultZipSettings(t *testing.T) {
+	ctx := testctx.NewWithCfg(config.Project{
+		Source: config.Source{
+			Files: []config.File{{
+				Source: "foo.png",
+				Zip:    config.ZipFileInfo{CompressionMethod: "store"},
+			}},
+		},
+	})
+	require.EqualError(t, Pipe{}.Default(ctx), "foo.png: zip settings are only used by the zip format, got [tar.gz]")
+	ctx.Config.Source.Format = "zip"
+	require.NoError(t, Pipe{}.Default(ctx))
+}
+
 func TestInvalidNameTemplate(t *testing.T) {
 	ctx := testctx.NewWithCfg(config.Project{
 		Source: config.Source{
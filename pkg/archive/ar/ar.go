@@ -0,0 +1,191 @@
+// Package ar implements the Archive interface providing ar archiving, as used
+// by Debian packages.
+package ar
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/closed"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/destination"
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+const (
+	// magic is the global header of ar archives.
+	magic = "!<arch>\n"
+
+	// maxNameLen is the maximum length of member names in the classic ar
+	// format.
+	maxNameLen = 16
+)
+
+// Archive as ar.
+//
+// Only the classic format is supported, so members can't be in directories,
+// nor have names longer than 16 characters.
+// Directories are ignored.
+type Archive struct {
+	w      io.Writer
+	files  map[string]bool
+	closed *closed.Flag
+}
+
+// New ar archive.
+func New(target io.Writer) Archive {
+	return Archive{
+		w:      target,
+		files:  map[string]bool{},
+		closed: &closed.Flag{},
+	}
+}
+
+// Format returns the archive format, "ar".
+func (a Archive) Format() string {
+	return "ar"
+}
+
+// Close writes the global header, if no members were added.
+func (a Archive) Close() error {
+	if err := a.closed.Close(); err != nil {
+		return err
+	}
+	if len(a.files) > 0 {
+		return nil
+	}
+	_, err := io.WriteString(a.w, magic)
+	return err
+}
+
+// Add file to the archive.
+func (a Archive) Add(f config.File) error {
+	// entry names always use forward slashes, even when built on Windows.
+	f.Destination = filepath.ToSlash(f.Destination)
+	if err := a.closed.Check(f.Destination); err != nil {
+		return err
+	}
+	if f.Source == "" && f.Info.Mode.IsDir() {
+		return nil
+	}
+	file, err := os.Open(f.Source) // #nosec
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+	mtime := info.ModTime()
+	if !f.Info.ParsedMTime.IsZero() {
+		mtime = f.Info.ParsedMTime
+	}
+	mode := info.Mode()
+	if f.Info.Mode != 0 {
+		mode = f.Info.Mode
+	}
+	return a.add(f.Destination, file, info.Size(), mode, mtime)
+}
+
+// AddWithReader adds a regular file to the archive, reading its content from
+// r, with the size, mode and modification time of the given info, unless set
+// in the file info.
+func (a Archive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	// entry names always use forward slashes, even when built on Windows.
+	f.Destination = filepath.ToSlash(f.Destination)
+	if err := a.closed.Check(f.Destination); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s: not a regular file: %s", f.Destination, info.Mode().Type())
+	}
+	mtime := info.ModTime()
+	if !f.Info.ParsedMTime.IsZero() {
+		mtime = f.Info.ParsedMTime
+	}
+	mode := info.Mode()
+	if f.Info.Mode != 0 {
+		mode = f.Info.Mode
+	}
+	return a.add(f.Destination, r, info.Size(), mode, mtime)
+}
+
+func (a Archive) add(dst string, r io.Reader, size int64, mode fs.FileMode, mtime time.Time) error {
+	if err := destination.Validate(dst); err != nil {
+		return err
+	}
+	if strings.Contains(dst, "/") || len(dst) > maxNameLen {
+		return fmt.Errorf("ar: invalid member name: %s", dst)
+	}
+	if _, ok := a.files[dst]; ok {
+		return &fs.PathError{Err: fs.ErrExist, Path: dst, Op: "add"}
+	}
+	var unix int64
+	if !mtime.IsZero() {
+		unix = mtime.Unix()
+	}
+	header, err := fileHeader(dst, unix, uint32(mode.Perm())|0o100000, size)
+	if err != nil {
+		return err
+	}
+	if len(a.files) == 0 {
+		if _, err := io.WriteString(a.w, magic); err != nil {
+			return err
+		}
+	}
+	a.files[dst] = true
+	if _, err := io.WriteString(a.w, header); err != nil {
+		return fmt.Errorf("%s: %w", dst, err)
+	}
+	if _, err := io.CopyN(a.w, r, size); err != nil {
+		return fmt.Errorf("%s: %w", dst, err)
+	}
+	// members are aligned to 2 bytes.
+	if size%2 != 0 {
+		if _, err := io.WriteString(a.w, "\n"); err != nil {
+			return fmt.Errorf("%s: %w", dst, err)
+		}
+	}
+	return nil
+}
+
+// fileHeader formats the header of a member, checking that each field fits
+// in its fixed width, as there is no way to extend them in the classic
+// format.
+func fileHeader(name string, mtime int64, mode uint32, size int64) (string, error) {
+	if mtime < 0 {
+		return "", fmt.Errorf("ar: %s: mtime out of range: %d", name, mtime)
+	}
+	if size < 0 {
+		return "", fmt.Errorf("ar: %s: size out of range: %d", name, size)
+	}
+	var header strings.Builder
+	for _, field := range []struct {
+		name  string
+		value string
+		width int
+	}{
+		{"name", name, maxNameLen},
+		{"mtime", strconv.FormatInt(mtime, 10), 12},
+		{"uid", "0", 6},
+		{"gid", "0", 6},
+		{"mode", strconv.FormatUint(uint64(mode), 8), 8},
+		{"size", strconv.FormatInt(size, 10), 10},
+	} {
+		if len(field.value) > field.width {
+			return "", fmt.Errorf("ar: %s: %s %s does not fit in %d bytes", name, field.name, field.value, field.width)
+		}
+		fmt.Fprintf(&header, "%-*s", field.width, field.value)
+	}
+	header.WriteString("`\n")
+	return header.String(), nil
+}
@@ -0,0 +1,180 @@
+package ar
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+type member struct {
+	name  string
+	mtime int64
+	mode  int64
+	size  int64
+	data  string
+}
+
+// readMembers parses the given ar archive, checking its headers and alignment.
+func readMembers(tb testing.TB, bts []byte) []member {
+	tb.Helper()
+	require.True(tb, bytes.HasPrefix(bts, []byte(magic)))
+	bts = bts[len(magic):]
+	var members []member
+	for len(bts) > 0 {
+		require.GreaterOrEqual(tb, len(bts), 60)
+		header := string(bts[:60])
+		require.Equal(tb, "`\n", header[58:])
+		mtime, err := strconv.ParseInt(strings.TrimSpace(header[16:28]), 10, 64)
+		require.NoError(tb, err)
+		mode, err := strconv.ParseInt(strings.TrimSpace(header[40:48]), 8, 64)
+		require.NoError(tb, err)
+		size, err := strconv.ParseInt(strings.TrimSpace(header[48:58]), 10, 64)
+		require.NoError(tb, err)
+		padded := size + size%2
+		require.GreaterOrEqual(tb, int64(len(bts)-60), padded)
+		if size%2 != 0 {
+			require.Equal(tb, byte('\n'), bts[60+size])
+		}
+		members = append(members, member{
+			name:  strings.TrimSpace(header[:16]),
+			mtime: mtime,
+			mode:  mode,
+			size:  size,
+			data:  string(bts[60 : 60+size]),
+		})
+		bts = bts[60+padded:]
+	}
+	return members
+}
+
+func TestArFile(t *testing.T) {
+	tmp := t.TempDir()
+	odd := filepath.Join(tmp, "odd.txt")
+	require.NoError(t, os.WriteFile(odd, []byte("odd"), 0o644))
+	now := time.Now().Truncate(time.Second)
+
+	path := filepath.Join(tmp, "test.a")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	archive := New(f)
+	defer archive.Close()
+
+	require.Error(t, archive.Add(config.File{
+		Source:      "../testdata/nope.txt",
+		Destination: "nope.txt",
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      odd,
+		Destination: "debian-binary",
+		Info: config.FileInfo{
+			ParsedMTime: now,
+		},
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/sub1",
+		Destination: "sub1",
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+		Info: config.FileInfo{
+			Mode: 0o600,
+		},
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/sub1/executable",
+		Destination: "executable",
+	}))
+	require.ErrorIs(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+	}), fs.ErrExist)
+	require.EqualError(t, archive.Add(config.File{
+		Source:      "../testdata/sub1/bar.txt",
+		Destination: "sub1/bar.txt",
+	}), "ar: invalid member name: sub1/bar.txt")
+	require.EqualError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "a-very-long-name.txt",
+	}), "ar: invalid member name: a-very-long-name.txt")
+	info, err := fs.Stat(fstest.MapFS{"fs.txt": {Mode: 0o644, Data: []byte("hello")}}, "fs.txt")
+	require.NoError(t, err)
+	require.NoError(t, archive.AddWithReader(config.File{Destination: "fs.txt"}, info, strings.NewReader("hello")))
+	require.NoError(t, archive.Close())
+	require.NoError(t, f.Close())
+
+	bts, err := os.ReadFile(path)
+	require.NoError(t, err)
+	members := readMembers(t, bts)
+	executable, err := os.Stat("../testdata/sub1/executable")
+	require.NoError(t, err)
+	require.Equal(t, []member{
+		{name: "debian-binary", mtime: now.Unix(), mode: 0o100644, size: 3, data: "odd"},
+		{name: "foo.txt", mtime: members[1].mtime, mode: 0o100600, size: 4, data: "foo\n"},
+		{name: "executable", mtime: members[2].mtime, mode: 0o100000 | int64(executable.Mode().Perm()), size: 0, data: ""},
+		{name: "fs.txt", mtime: 0, mode: 0o100644, size: 5, data: "hello"},
+	}, members)
+
+	if _, err := exec.LookPath("ar"); err == nil {
+		out, err := exec.Command("ar", "t", path).CombinedOutput()
+		require.NoError(t, err, string(out))
+		require.Equal(t, "debian-binary\nfoo.txt\nexecutable\nfs.txt\n", string(out))
+	}
+}
+
+func TestArEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	archive := New(&buf)
+	require.Equal(t, "ar", archive.Format())
+	require.NoError(t, archive.Close())
+	require.Equal(t, magic, buf.String())
+	require.Empty(t, readMembers(t, buf.Bytes()))
+}
+
+func TestArUnsafeDestination(t *testing.T) {
+	archive := New(io.Discard)
+	require.Error(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "../foo.txt",
+	}))
+}
+
+func TestArOutOfRange(t *testing.T) {
+	info, err := fs.Stat(fstest.MapFS{"big.bin": {Mode: 0o644}}, "big.bin")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	archive := New(&buf)
+	// the content is never read, as the header is rejected first.
+	require.EqualError(t, archive.AddWithReader(config.File{Destination: "big.bin"}, sizedInfo{info, 10_000_000_000}, strings.NewReader("")),
+		"ar: big.bin: size 10000000000 does not fit in 10 bytes")
+	require.EqualError(t, archive.AddWithReader(config.File{
+		Destination: "future.bin",
+		Info:        config.FileInfo{ParsedMTime: time.Unix(1_000_000_000_000, 0)},
+	}, info, strings.NewReader("")), "ar: future.bin: mtime 1000000000000 does not fit in 12 bytes")
+	require.EqualError(t, archive.AddWithReader(config.File{
+		Destination: "old.bin",
+		Info:        config.FileInfo{ParsedMTime: time.Unix(-1, 0)},
+	}, info, strings.NewReader("")), "ar: old.bin: mtime out of range: -1")
+	require.Zero(t, buf.Len())
+}
+
+// sizedInfo is a file info with the given size.
+type sizedInfo struct {
+	fs.FileInfo
+	size int64
+}
+
+func (i sizedInfo) Size() int64 { return i.size }
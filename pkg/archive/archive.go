@@ -2,11 +2,28 @@
 package archive
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"maps"
+	"net/http"
 	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/ar"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/cpio"
 	"github.com/goreleaser/goreleaser/v2/pkg/archive/gzip"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/closed"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/counting"
 	"github.com/goreleaser/goreleaser/v2/pkg/archive/tar"
 	"github.com/goreleaser/goreleaser/v2/pkg/archive/targz"
 	"github.com/goreleaser/goreleaser/v2/pkg/archive/tarxz"
@@ -16,30 +33,551 @@ import (
 )
 
 // Archive represents a compression archive files from disk can be written to.
+//
+// Empty directories can be added by leaving the file source empty and setting
+// its mode to a directory, e.g. os.ModeDir|0o755.
 type Archive interface {
 	Close() error
 	Add(f config.File) error
+	Format() string
+}
+
+var (
+	// ErrClosed happens when adding files to, or closing, an archive which
+	// was already closed.
+	ErrClosed = closed.ErrClosed
+
+	// ErrDuplicateEntry happens when adding a file to a destination which is
+	// already in the archive.
+	// It is the same as [fs.ErrExist], so both can be used with [errors.Is].
+	ErrDuplicateEntry = fs.ErrExist
+
+	// ErrCopyUnsupported happens when [Copy] is used with a format which
+	// can't be appended to.
+	ErrCopyUnsupported = errors.New("copy not supported")
+)
+
+// Flusher is implemented by archives which can write their buffered data to
+// the underlying writer before being closed, so the progress is not lost if
+// something goes wrong midway.
+//
+// It is implemented by the tar, tar.gz and tar.zst formats.
+type Flusher interface {
+	Flush() error
+}
+
+// Option customizes the archive created by [New].
+type Option func(*options)
+
+type options struct {
+	threads         int
+	caseInsensitive bool
+	sparse          bool
+	xattrs          bool
+	zstd            tarzst.Options
+	hasher          *Hasher
+	duplicates      *Duplicates
+	prefix          string
+	bufSize         int
+	rejectLinks     bool
+	rewriteLinks    bool
+	stats           bool
+	bytesIn         *int64
+	comment         string
+	level           int
+	reproducible    bool
+	clampMTime      time.Time
+	resolveOwners   bool
+	rejectEmpty     bool
+	allowedEmpty    []string
+	permMask        fs.FileMode
+	httpClient      *http.Client
+	transform       func(string) string
+	onAdd           func(config.File, fs.FileInfo)
+	recordSize      int
+}
+
+func (o options) tarOptions() []tar.Option {
+	var opts []tar.Option
+	if o.caseInsensitive {
+		opts = append(opts, tar.WithCaseInsensitiveCheck())
+	}
+	if o.sparse {
+		opts = append(opts, tar.WithSparseFiles())
+	}
+	if o.xattrs {
+		opts = append(opts, tar.WithXattrs())
+	}
+	if o.bufSize > 0 {
+		opts = append(opts, tar.WithCopyBufferSize(o.bufSize))
+	}
+	if o.rejectLinks {
+		opts = append(opts, tar.WithRejectUnsafeSymlinks())
+	}
+	if o.rewriteLinks {
+		opts = append(opts, tar.WithRewriteUnsafeSymlinks())
+	}
+	if o.resolveOwners {
+		opts = append(opts, tar.WithResolveOwnerNames())
+	}
+	if o.recordSize > 0 {
+		opts = append(opts, tar.WithRecordSize(o.recordSize))
+	}
+	return opts
+}
+
+func (o options) zipOptions() []zip.Option {
+	var opts []zip.Option
+	if o.caseInsensitive {
+		opts = append(opts, zip.WithCaseInsensitiveCheck())
+	}
+	if o.bufSize > 0 {
+		opts = append(opts, zip.WithCopyBufferSize(o.bufSize))
+	}
+	if o.comment != "" {
+		opts = append(opts, zip.WithComment(o.comment))
+	}
+	if o.level != 0 {
+		opts = append(opts, zip.WithLevel(o.level))
+	}
+	if o.reproducible {
+		opts = append(opts, zip.WithReproducible())
+	}
+	return opts
+}
+
+// WithThreads sets the number of threads used to compress the archive.
+//
+// More than 1 thread requires the `xz` binary, whose output depends on the
+// host, see [tarxz.NewWithThreads], so it is ignored along with
+// [WithReproducible].
+// If it is not in the $PATH, a single thread is used instead.
+//
+// Only used by the tar.xz format, ignored by all others.
+func WithThreads(n int) Option {
+	return func(o *options) {
+		o.threads = n
+	}
+}
+
+// WithXattrs makes the extended attributes of the added files be preserved.
+//
+// Only used by the tar based formats, on Linux, ignored by all others.
+func WithXattrs() Option {
+	return func(o *options) {
+		o.xattrs = true
+	}
+}
+
+// WithZstdOptions sets the zstd encoder options, such as the window size and
+// dictionary.
+//
+// Only used by the tar.zst format, ignored by all others.
+func WithZstdOptions(zo tarzst.Options) Option {
+	return func(o *options) {
+		o.zstd = zo
+	}
+}
+
+// WithHasher makes everything written to the archive output also be written
+// to the given [Hasher], so its checksums can be read once the archive is
+// closed.
+func WithHasher(h *Hasher) Option {
+	return func(o *options) {
+		o.hasher = h
+	}
+}
+
+// WithCaseInsensitiveCheck makes Add fail when the destination only differs
+// by case from a previously added one.
+//
+// Ignored by the gz format, as it can only hold a single file.
+func WithCaseInsensitiveCheck() Option {
+	return func(o *options) {
+		o.caseInsensitive = true
+	}
+}
+
+// WithSparseFiles makes files with holes be archived as sparse entries.
+//
+// Only used by the tar based formats, ignored by all others.
+func WithSparseFiles() Option {
+	return func(o *options) {
+		o.sparse = true
+	}
+}
+
+// WithCopyBufferSize sets the size of the buffer used to copy the content of
+// the added files, which can speed up adding large files.
+// Zero keeps the default size.
+//
+// Only used by the tar based and zip formats, ignored by all others.
+func WithCopyBufferSize(size int) Option {
+	return func(o *options) {
+		o.bufSize = size
+	}
+}
+
+// WithRejectUnsafeSymlinks makes Add fail when adding a symlink whose target
+// is absolute or escapes the archive root.
+//
+// Only used by the tar based formats, ignored by all others.
+func WithRejectUnsafeSymlinks() Option {
+	return func(o *options) {
+		o.rejectLinks = true
+	}
+}
+
+// WithRewriteUnsafeSymlinks makes the targets of symlinks which are absolute
+// or escape the archive root be rewritten to relative ones inside it.
+// It takes precedence over [WithRejectUnsafeSymlinks].
+//
+// Only used by the tar based formats, ignored by all others.
+func WithRewriteUnsafeSymlinks() Option {
+	return func(o *options) {
+		o.rewriteLinks = true
+	}
+}
+
+// WithResolveOwnerNames makes the owner and group names in the file info be
+// resolved to their numeric uid and gid, failing if they can't be.
+//
+// Only used by the tar based formats, ignored by all others.
+func WithResolveOwnerNames() Option {
+	return func(o *options) {
+		o.resolveOwners = true
+	}
+}
+
+// WithRecordSize makes the uncompressed tar stream be padded to a multiple of
+// the given record size, which must be a multiple of 512 bytes, e.g. 10240.
+//
+// Only used by the tar based formats, ignored by all others.
+func WithRecordSize(size int) Option {
+	return func(o *options) {
+		o.recordSize = size
+	}
+}
+
+// WithComment sets the comment of the whole archive.
+//
+// Only used by the zip format, ignored by all others.
+func WithComment(comment string) Option {
+	return func(o *options) {
+		o.comment = comment
+	}
+}
+
+// WithLevel sets the compression level, in the range supported by the format.
+// Zero keeps the default level of each format, usually the best compression.
+//
+// Only used by the tar.gz, tar.zst and zip formats, ignored by all others.
+func WithLevel(level int) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithReproducible makes the archive reproducible, by writing its entries
+// sorted by name and normalizing their headers.
+//
+// Only used by the zip format, and by the tar.xz one to ignore [WithThreads],
+// ignored by all others.
+func WithReproducible() Option {
+	return func(o *options) {
+		o.reproducible = true
+	}
+}
+
+// Options are the options common to all formats, as a struct, for callers
+// which have them as configuration.
+// Zero values keep the defaults.
+type Options struct {
+	// Level is the compression level, see [WithLevel].
+	Level int
+
+	// Reproducible makes the archive reproducible, see [WithReproducible].
+	Reproducible bool
+
+	// Prefix is the directory all entries are added to, see [WithPrefix].
+	Prefix string
+
+	// ClampMTime is the latest modification time of the entries, see
+	// [WithClampMTime].
+	ClampMTime time.Time
+}
+
+// NewWithCommonOptions creates an archive with the given options, applying the
+// ones the format supports, and ignoring the others.
+func NewWithCommonOptions(w io.Writer, format string, o Options) (Archive, error) {
+	var opts []Option
+	if o.Level != 0 {
+		opts = append(opts, WithLevel(o.Level))
+	}
+	if o.Reproducible {
+		opts = append(opts, WithReproducible())
+	}
+	if o.Prefix != "" {
+		opts = append(opts, WithPrefix(o.Prefix))
+	}
+	if !o.ClampMTime.IsZero() {
+		opts = append(opts, WithClampMTime(o.ClampMTime))
+	}
+	return New(w, format, opts...)
 }
 
 // New archive.
-func New(w io.Writer, format string) (Archive, error) {
-	switch format {
-	case "tar.gz", "tgz":
-		return targz.New(w), nil
-	case "tar":
-		return tar.New(w), nil
-	case "gz":
+func New(w io.Writer, format string, opts ...Option) (Archive, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.hasher != nil {
+		w = io.MultiWriter(w, o.hasher)
+	}
+	var out *counting.Writer
+	if o.stats {
+		out = &counting.Writer{W: w}
+		w = out
+		o.bytesIn = new(int64)
+	}
+	a, err := newArchive(w, format, o)
+	if err != nil {
+		return nil, err
+	}
+	a = o.decorate(a)
+	if out != nil {
+		a = statsArchive{Archive: a, out: out, in: o.bytesIn}
+	}
+	return a, nil
+}
+
+// entryName returns the name in the archive of the given destination, as
+// prefixed and transformed by the decorators.
+func (o options) entryName(dst string) string {
+	if o.prefix != "" {
+		dst = path.Join(o.prefix, dst)
+	}
+	if o.transform != nil {
+		dst = o.transform(dst)
+	}
+	return dst
+}
+
+// decorate wraps the given archive with the decorators enabled by the options.
+func (o options) decorate(a Archive) Archive {
+	if o.bytesIn != nil {
+		a = bytesInArchive{Archive: a, n: o.bytesIn}
+	}
+	if o.onAdd != nil {
+		a = onAddArchive{Archive: a, fn: o.onAdd}
+	}
+	if !o.clampMTime.IsZero() {
+		a = clampArchive{Archive: a, mtime: o.clampMTime}
+	}
+	if o.permMask != 0 {
+		a = permMaskArchive{Archive: a, mask: o.permMask}
+	}
+	if o.duplicates != nil {
+		a = duplicatesArchive{Archive: a, d: o.duplicates}
+	}
+	if o.rejectEmpty {
+		a = rejectEmptyArchive{Archive: a, allowed: o.allowedEmpty}
+	}
+	if o.httpClient != nil {
+		a = urlArchive{Archive: a, client: o.httpClient}
+	}
+	if o.transform != nil {
+		a = transformArchive{Archive: a, transform: o.transform}
+	}
+	if o.prefix != "" {
+		a = prefixArchive{Archive: a, prefix: o.prefix}
+	}
+	return a
+}
+
+// builtins are the built-in archive formats, by canonical name.
+var builtins = map[string]func(w io.Writer, o options) (Archive, error){
+	"tar.gz": func(w io.Writer, o options) (Archive, error) {
+		if o.level != 0 {
+			return targz.NewWithLevel(w, o.level, o.tarOptions()...)
+		}
+		return targz.New(w, o.tarOptions()...), nil
+	},
+	"tar": func(w io.Writer, o options) (Archive, error) {
+		return tar.New(w, o.tarOptions()...), nil
+	},
+	"gz": func(w io.Writer, _ options) (Archive, error) {
 		return gzip.New(w), nil
-	case "tar.xz", "txz":
-		return tarxz.New(w), nil
-	case "tar.zst", "tzst":
-		return tarzst.New(w), nil
-	case "zip":
-		return zip.New(w), nil
+	},
+	"tar.xz": func(w io.Writer, o options) (Archive, error) {
+		if o.reproducible {
+			return tarxz.New(w, o.tarOptions()...), nil
+		}
+		a, err := tarxz.NewWithThreads(w, o.threads, o.tarOptions()...)
+		if errors.Is(err, exec.ErrNotFound) {
+			return tarxz.New(w, o.tarOptions()...), nil
+		}
+		return a, err
+	},
+	"tar.zst": func(w io.Writer, o options) (Archive, error) {
+		zo := o.zstd
+		if zo.Level == 0 {
+			zo.Level = o.level
+		}
+		return tarzst.NewWithOptions(w, zo, o.tarOptions()...)
+	},
+	"zip": func(w io.Writer, o options) (Archive, error) {
+		return zip.New(w, o.zipOptions()...), nil
+	},
+	"ar": func(w io.Writer, _ options) (Archive, error) {
+		return ar.New(w), nil
+	},
+	"cpio": func(w io.Writer, _ options) (Archive, error) {
+		return cpio.New(w), nil
+	},
+}
+
+// aliases maps the alternative names of built-in formats to their canonical
+// ones.
+var aliases = map[string]string{
+	"tgz":  "tar.gz",
+	"txz":  "tar.xz",
+	"tzst": "tar.zst",
+}
+
+// SupportedFormats returns the canonical names of all the formats supported
+// by [New], built-in and registered, sorted.
+// Alternative names of built-in formats are returned by [Aliases].
+func SupportedFormats() []string {
+	formats := slices.Collect(maps.Keys(builtins))
+	registryMu.RLock()
+	for format := range registry {
+		if _, ok := builtins[format]; !ok {
+			formats = append(formats, format)
+		}
+	}
+	registryMu.RUnlock()
+	slices.Sort(formats)
+	return formats
+}
+
+// Aliases returns the alternative names of built-in formats, e.g. "tgz",
+// mapped to their canonical ones, e.g. "tar.gz".
+func Aliases() map[string]string {
+	return maps.Clone(aliases)
+}
+
+func newArchive(w io.Writer, format string, o options) (Archive, error) {
+	if canonical, ok := aliases[format]; ok {
+		format = canonical
+	}
+	if factory, ok := builtins[format]; ok {
+		return factory(w, o)
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if factory, ok := registry[format]; ok {
+		return factory(w), nil
 	}
 	return nil, fmt.Errorf("invalid archive format: %s", format)
 }
 
+// OpenForAppend opens the archive in the given file name and format, so new
+// files can be added to it, without rewriting the existing ones.
+//
+// Only the zip format is supported.
+// As only the new files are written, [WithHasher] and [WithStats] are not
+// supported.
+func OpenForAppend(filename, format string, opts ...Option) (Archive, error) {
+	if format != "zip" {
+		return nil, fmt.Errorf("append not supported for archive format: %s", format)
+	}
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.hasher != nil || o.stats {
+		return nil, errors.New("append not supported with hashers or stats")
+	}
+	f, err := os.OpenFile(filename, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	a, err := zip.OpenForAppend(f, o.zipOptions()...)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return o.decorate(fileArchive{Archive: a, f: f}), nil
+}
+
+// fileArchive is an archive which also closes its file.
+type fileArchive struct {
+	Archive
+	f *os.File
+}
+
+func (a fileArchive) unwrap() Archive { return a.Archive }
+
+func (a fileArchive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	return addReader(a.Archive, f, info, r)
+}
+
+func (a fileArchive) Close() error {
+	if err := a.Archive.Close(); err != nil {
+		_ = a.f.Close()
+		return err
+	}
+	return a.f.Close()
+}
+
+// CreateArchive creates an archive in the given file name and format, containing
+// the given files.
+//
+// The archive file is removed if anything goes wrong.
+func CreateArchive(filename, format string, files []config.File, opts ...Option) (err error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(filename)
+		}
+	}()
+	a, err := New(f, format, opts...)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := a.Add(file); err != nil {
+			_ = a.Close()
+			return fmt.Errorf("could not add %s to %s: %w", file.Source, filename, err)
+		}
+	}
+	if err := a.Close(); err != nil {
+		return fmt.Errorf("could not close %s: %w", filename, err)
+	}
+	return f.Close()
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]func(io.Writer) Archive{}
+)
+
+// Register makes an archive format available to [New].
+//
+// Built-in formats take precedence over registered ones, and registering the
+// same format twice replaces the previous factory.
+func Register(format string, factory func(io.Writer) Archive) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[format] = factory
+}
+
 // Copy copies the source archive into a new one, which can be appended at.
 // Source needs to be in the specified format.
 func Copy(r *os.File, w io.Writer, format string) (Archive, error) {
@@ -50,6 +588,111 @@ func Copy(r *os.File, w io.Writer, format string) (Archive, error) {
 		return tar.Copy(r, w)
 	case "zip":
 		return zip.Copy(r, w)
+	case "gz":
+		return nil, copyError("gz archives can only hold a single file, so they do not support append")
+	case "tar.xz", "txz", "tar.zst", "tzst", "ar", "cpio":
+		return nil, fmt.Errorf("%w for archive format: %s", ErrCopyUnsupported, format)
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if _, ok := registry[format]; ok {
+		return nil, fmt.Errorf("%w for archive format: %s", ErrCopyUnsupported, format)
 	}
 	return nil, fmt.Errorf("invalid archive format: %s", format)
 }
+
+// copyError is an [ErrCopyUnsupported] with a more specific message.
+type copyError string
+
+func (e copyError) Error() string { return string(e) }
+
+func (copyError) Unwrap() error { return ErrCopyUnsupported }
+
+// CopyVerify works like [Copy], but first checks that the SHA256 checksum of
+// the source matches the given hex-encoded one, failing if it doesn't.
+func CopyVerify(r *os.File, w io.Writer, format, wantSHA string) (Archive, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("could not verify %s: %w", r.Name(), err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, fmt.Errorf("could not verify %s: %w", r.Name(), err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, wantSHA) {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", r.Name(), wantSHA, got)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("could not verify %s: %w", r.Name(), err)
+	}
+	return Copy(r, w, format)
+}
+
+// SizeEstimate returns the size of an archive in the given format containing
+// the given files.
+//
+// Only tar based formats are supported, and for the compressed ones
+// (e.g. tar.gz) the size of the uncompressed tar is returned, which is its
+// worst case scenario, as compression usually makes it smaller.
+func SizeEstimate(files []config.File, format string) (int64, error) {
+	switch format {
+	case "tar", "tar.gz", "tgz", "tar.xz", "txz", "tar.zst", "tzst":
+		return tar.EstimateSize(files)
+	}
+	return 0, fmt.Errorf("size estimate not supported for archive format: %s", format)
+}
+
+// AddDir adds all the files inside the src directory to the archive, with
+// their paths relative to src joined to dst.
+//
+// Files and directories matching any of the exclude patterns are skipped, and
+// excluded directories are not walked into.
+// Patterns use the [path.Match] syntax, and are matched against both the path
+// relative to src and the file name, so, for instance, both "*.tmp" and
+// "sub/*.tmp" exclude "sub/foo.tmp".
+func AddDir(a Archive, src, dst string, exclude []string) error {
+	return filepath.WalkDir(src, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, name)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		excluded, err := matchesAny(exclude, rel)
+		if err != nil {
+			return err
+		}
+		if excluded {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		return a.Add(config.File{
+			Source:      name,
+			Destination: path.Join(dst, rel),
+		})
+	})
+}
+
+func matchesAny(patterns []string, rel string) (bool, error) {
+	for _, pattern := range patterns {
+		for _, name := range []string{rel, path.Base(rel)} {
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return false, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+			}
+			if ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
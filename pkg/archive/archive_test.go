@@ -1,12 +1,31 @@
 package archive
 
 import (
+	stdzip "archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"math/rand/v2"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/goreleaser/goreleaser/v2/internal/testlib"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/destination"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/tarzst"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/zip"
 	"github.com/goreleaser/goreleaser/v2/pkg/config"
 	"github.com/stretchr/testify/require"
 )
@@ -70,9 +89,746 @@ func TestArchive(t *testing.T) {
 		})
 	}
 
+	for _, format := range []string{"tar.gz", "zip", "gz", "tar.xz", "tar", "tar.zst"} {
+		t.Run(format+" unsafe destination", func(t *testing.T) {
+			archive, err := New(io.Discard, format)
+			require.NoError(t, err)
+			defer archive.Close()
+			for _, dst := range []string{
+				"../../etc/passwd",
+				"/etc/passwd",
+				"sub/../../passwd",
+			} {
+				require.ErrorIs(t, archive.Add(config.File{
+					Source:      empty.Name(),
+					Destination: dst,
+				}), destination.ErrUnsafe)
+			}
+		})
+	}
+
+	t.Run("gz copy", func(t *testing.T) {
+		_, err := Copy(empty, io.Discard, "gz")
+		require.EqualError(t, err, "gz archives can only hold a single file, so they do not support append")
+		require.ErrorIs(t, err, ErrCopyUnsupported)
+	})
+
 	// unsupported format...
 	t.Run("7z", func(t *testing.T) {
 		_, err := New(io.Discard, "7z")
 		require.EqualError(t, err, "invalid archive format: 7z")
 	})
 }
+
+func TestArchiveCaseInsensitiveCheck(t *testing.T) {
+	for _, format := range []string{"tar.gz", "zip", "tar.xz", "tar", "tar.zst"} {
+		t.Run(format, func(t *testing.T) {
+			t.Run("enabled", func(t *testing.T) {
+				archive, err := New(io.Discard, format, WithCaseInsensitiveCheck())
+				require.NoError(t, err)
+				defer archive.Close()
+				require.NoError(t, archive.Add(config.File{
+					Source:      "testdata/foo.txt",
+					Destination: "README",
+				}))
+				require.ErrorIs(t, archive.Add(config.File{
+					Source:      "testdata/foo.txt",
+					Destination: "readme",
+				}), fs.ErrExist)
+			})
+
+			t.Run("disabled", func(t *testing.T) {
+				archive, err := New(io.Discard, format)
+				require.NoError(t, err)
+				defer archive.Close()
+				require.NoError(t, archive.Add(config.File{
+					Source:      "testdata/foo.txt",
+					Destination: "README",
+				}))
+				require.NoError(t, archive.Add(config.File{
+					Source:      "testdata/foo.txt",
+					Destination: "readme",
+				}))
+			})
+		})
+	}
+}
+
+func TestSizeEstimate(t *testing.T) {
+	files := []config.File{
+		{Source: "testdata/foo.txt", Destination: "foo.txt"},
+		{Source: "testdata/sub1", Destination: "sub1"},
+		{Source: "testdata/sub1/bar.txt", Destination: "sub1/bar.txt"},
+		{Source: "testdata/sub1/executable", Destination: "sub1/executable"},
+		{Source: "testdata/sub1/sub2/subfoo.txt", Destination: "sub1/sub2/" + strings.Repeat("a", 150) + ".txt"},
+		{Destination: "logs", Info: config.FileInfo{Mode: os.ModeDir | 0o755}},
+	}
+
+	for _, format := range []string{"tar", "tar.gz", "tar.xz", "tar.zst"} {
+		t.Run(format, func(t *testing.T) {
+			size, err := SizeEstimate(files, format)
+			require.NoError(t, err)
+
+			var uncompressed bytes.Buffer
+			archive, err := New(&uncompressed, "tar")
+			require.NoError(t, err)
+			for _, f := range files {
+				require.NoError(t, archive.Add(f))
+			}
+			require.NoError(t, archive.Close())
+			require.Equal(t, int64(uncompressed.Len()), size)
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := SizeEstimate(files, "zip")
+		require.EqualError(t, err, "size estimate not supported for archive format: zip")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := SizeEstimate([]config.File{{Source: "testdata/nope.txt", Destination: "nope.txt"}}, "tar")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestCopyVerify(t *testing.T) {
+	f1, err := os.Create(filepath.Join(t.TempDir(), "1.tar.gz"))
+	require.NoError(t, err)
+	archive, err := New(f1, "tar.gz")
+	require.NoError(t, err)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	require.NoError(t, archive.Close())
+	require.NoError(t, f1.Close())
+
+	bts, err := os.ReadFile(f1.Name())
+	require.NoError(t, err)
+	sum := sha256.Sum256(bts)
+	sha := hex.EncodeToString(sum[:])
+
+	t.Run("match", func(t *testing.T) {
+		f1, err := os.Open(f1.Name())
+		require.NoError(t, err)
+		defer f1.Close()
+		f2, err := os.Create(filepath.Join(t.TempDir(), "2.tar.gz"))
+		require.NoError(t, err)
+		defer f2.Close()
+
+		a, err := CopyVerify(f1, f2, "tar.gz", strings.ToUpper(sha))
+		require.NoError(t, err)
+		require.NoError(t, a.Add(config.File{
+			Source:      "testdata/regular.txt",
+			Destination: "regular.txt",
+		}))
+		require.NoError(t, a.Close())
+		require.NoError(t, f2.Close())
+		require.Equal(t, []string{"foo.txt", "regular.txt"}, testlib.LsArchive(t, f2.Name(), "tar.gz"))
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		f1, err := os.Open(f1.Name())
+		require.NoError(t, err)
+		defer f1.Close()
+
+		_, err = CopyVerify(f1, io.Discard, "tar.gz", strings.Repeat("0", 64))
+		require.ErrorContains(t, err, "checksum mismatch for "+f1.Name())
+		require.ErrorContains(t, err, "got "+sha)
+	})
+}
+
+func TestArchiveAddFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"foo.txt":             {Data: []byte("foo"), Mode: 0o644},
+		"sub/bar.txt":         {Data: []byte("bar"), Mode: 0o600},
+		"sub/deeper/exec.sh":  {Data: []byte("#!/bin/sh"), Mode: 0o755},
+		"sub/deeper/empty.md": {Mode: 0o644},
+	}
+
+	for _, format := range []string{"tar.gz", "zip", "tar.xz", "tar"} {
+		t.Run(format, func(t *testing.T) {
+			f, err := os.Create(filepath.Join(t.TempDir(), "archive"))
+			require.NoError(t, err)
+			archive, err := New(f, format)
+			require.NoError(t, err)
+			require.NoError(t, AddFS(archive, fsys, "templates"))
+			require.ErrorIs(t, AddFS(archive, fsys, "templates"), fs.ErrExist)
+			require.NoError(t, archive.Close())
+			require.NoError(t, f.Close())
+
+			require.ElementsMatch(t, []string{
+				"templates/foo.txt",
+				"templates/sub/bar.txt",
+				"templates/sub/deeper/exec.sh",
+				"templates/sub/deeper/empty.md",
+			}, testlib.LsArchive(t, f.Name(), format))
+			require.Equal(t, []byte("#!/bin/sh"), testlib.GetFileFromArchive(t, f.Name(), format, "templates/sub/deeper/exec.sh"))
+		})
+	}
+
+	t.Run("gz", func(t *testing.T) {
+		f, err := os.Create(filepath.Join(t.TempDir(), "archive.gz"))
+		require.NoError(t, err)
+		archive, err := New(f, "gz")
+		require.NoError(t, err)
+		require.NoError(t, AddFS(archive, fstest.MapFS{
+			"foo.txt": {Data: []byte("foo"), Mode: 0o644},
+		}, ""))
+		require.Error(t, AddFS(archive, fsys, ""))
+		require.NoError(t, archive.Close())
+		require.NoError(t, f.Close())
+		require.Equal(t, []string{"foo.txt"}, testlib.LsArchive(t, f.Name(), "gz"))
+		require.Equal(t, []byte("foo"), testlib.GetFileFromArchive(t, f.Name(), "gz", "foo.txt"))
+	})
+
+	t.Run("open error", func(t *testing.T) {
+		f, err := os.Create(filepath.Join(t.TempDir(), "archive.tar"))
+		require.NoError(t, err)
+		archive, err := New(f, "tar")
+		require.NoError(t, err)
+		require.ErrorIs(t, AddFS(archive, openErrorFS{fstest.MapFS{
+			"bad": {Data: []byte("bad")},
+		}}, ""), fs.ErrPermission)
+		require.NoError(t, archive.Add(config.File{Source: "testdata/foo.txt", Destination: "foo.txt"}))
+		require.NoError(t, archive.Close())
+		require.NoError(t, f.Close())
+		require.Equal(t, []string{"foo.txt"}, testlib.LsArchive(t, f.Name(), "tar"))
+	})
+
+	t.Run("without readers", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive := &fakeArchive{w: &buf}
+		require.NoError(t, AddFS(archive, fsys, "templates"))
+		require.ElementsMatch(t, []string{
+			"templates/foo.txt",
+			"templates/sub/bar.txt",
+			"templates/sub/deeper/exec.sh",
+			"templates/sub/deeper/empty.md",
+		}, archive.added)
+	})
+}
+
+// openErrorFS fails to open any of its files.
+type openErrorFS struct {
+	fstest.MapFS
+}
+
+func (f openErrorFS) Open(name string) (fs.File, error) {
+	if name == "." {
+		return f.MapFS.Open(name)
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+}
+
+type fakeArchive struct {
+	w     io.Writer
+	added []string
+}
+
+func (a *fakeArchive) Close() error { return nil }
+
+func (a *fakeArchive) Add(f config.File) error {
+	a.added = append(a.added, f.Destination)
+	_, err := io.WriteString(a.w, f.Destination+"\n")
+	return err
+}
+
+func (a *fakeArchive) Format() string { return "fake" }
+
+func TestRegister(t *testing.T) {
+	Register("fake", func(w io.Writer) Archive {
+		return &fakeArchive{w: w}
+	})
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		delete(registry, "fake")
+	})
+
+	var buf bytes.Buffer
+	archive, err := New(&buf, "fake")
+	require.NoError(t, err)
+	require.IsType(t, &fakeArchive{}, archive)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	require.NoError(t, archive.Close())
+	require.Equal(t, "foo.txt\n", buf.String())
+
+	t.Run("built-ins take precedence", func(t *testing.T) {
+		Register("zip", func(w io.Writer) Archive {
+			return &fakeArchive{w: w}
+		})
+		t.Cleanup(func() {
+			registryMu.Lock()
+			defer registryMu.Unlock()
+			delete(registry, "zip")
+		})
+		archive, err := New(io.Discard, "zip")
+		require.NoError(t, err)
+		require.IsType(t, zip.Archive{}, archive)
+	})
+}
+
+func TestSupportedFormats(t *testing.T) {
+	Register("fake", func(w io.Writer) Archive {
+		return &fakeArchive{w: w}
+	})
+	Register("zip", func(w io.Writer) Archive {
+		return &fakeArchive{w: w}
+	})
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		delete(registry, "fake")
+		delete(registry, "zip")
+	})
+
+	formats := SupportedFormats()
+	require.Subset(t, formats, []string{
+		"ar", "cpio", "fake", "gz", "tar", "tar.gz", "tar.xz", "tar.zst", "zip",
+	})
+	require.True(t, slices.IsSorted(formats))
+	require.Len(t, slices.Compact(slices.Clone(formats)), len(formats))
+	for _, format := range formats {
+		t.Run(format, func(t *testing.T) {
+			_, err := New(io.Discard, format)
+			require.NoError(t, err)
+		})
+	}
+
+	require.Equal(t, map[string]string{
+		"tgz":  "tar.gz",
+		"txz":  "tar.xz",
+		"tzst": "tar.zst",
+	}, Aliases())
+	for alias, format := range Aliases() {
+		t.Run(alias, func(t *testing.T) {
+			require.Contains(t, formats, format)
+			archive, err := New(io.Discard, alias)
+			require.NoError(t, err)
+			require.Equal(t, format, archive.Format())
+		})
+	}
+}
+
+func TestAddDir(t *testing.T) {
+	src := t.TempDir()
+	for _, name := range []string{
+		"main.go",
+		"notes.tmp",
+		"docs/README.md",
+		"docs/draft.tmp",
+		".git/HEAD",
+		".git/refs/heads/main",
+		"web/node_modules/pkg/index.js",
+		"web/index.js",
+		"logs/app.log",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(src, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(src, name), []byte(name), 0o644))
+	}
+
+	for _, format := range []string{"tar.gz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			f, err := os.Create(filepath.Join(t.TempDir(), "archive"))
+			require.NoError(t, err)
+			archive, err := New(f, format)
+			require.NoError(t, err)
+			require.NoError(t, AddDir(archive, src, "app", []string{
+				".git",
+				"*.tmp",
+				"node_modules",
+				"logs/*.log",
+			}))
+			require.NoError(t, archive.Close())
+			require.NoError(t, f.Close())
+
+			require.ElementsMatch(t, []string{
+				"app/main.go",
+				"app/docs/README.md",
+				"app/web/index.js",
+			}, testlib.LsArchive(t, f.Name(), format))
+		})
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar")
+		require.NoError(t, err)
+		defer archive.Close()
+		require.ErrorIs(t, AddDir(archive, src, "", []string{"[a-"}), path.ErrBadPattern)
+	})
+
+	t.Run("missing dir", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar")
+		require.NoError(t, err)
+		defer archive.Close()
+		require.ErrorIs(t, AddDir(archive, filepath.Join(src, "nope"), "", nil), fs.ErrNotExist)
+	})
+}
+
+type failingCloseArchive struct{ fakeArchive }
+
+func (*failingCloseArchive) Close() error { return errors.New("fake close error") }
+
+func TestCreateArchive(t *testing.T) {
+	files := []config.File{
+		{Source: "testdata/foo.txt", Destination: "foo.txt"},
+		{Source: "testdata/sub1/bar.txt", Destination: "sub1/bar.txt"},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "archive.tar.gz")
+		require.NoError(t, CreateArchive(path, "tar.gz", files))
+		require.Equal(t, []string{"foo.txt", "sub1/bar.txt"}, testlib.LsArchive(t, path, "tar.gz"))
+	})
+
+	t.Run("with options", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "archive.zip")
+		require.ErrorIs(t, CreateArchive(path, "zip", append(files, config.File{
+			Source:      "testdata/foo.txt",
+			Destination: "FOO.txt",
+		}), WithCaseInsensitiveCheck()), fs.ErrExist)
+		require.NoFileExists(t, path)
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "archive.7z")
+		require.EqualError(t, CreateArchive(path, "7z", files), "invalid archive format: 7z")
+		require.NoFileExists(t, path)
+	})
+
+	t.Run("add error", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "archive.tar")
+		err := CreateArchive(path, "tar", append(files, config.File{
+			Source:      "testdata/nope.txt",
+			Destination: "nope.txt",
+		}))
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		require.ErrorContains(t, err, "could not add testdata/nope.txt to "+path)
+		require.NoFileExists(t, path)
+	})
+
+	t.Run("close error", func(t *testing.T) {
+		Register("failing", func(w io.Writer) Archive {
+			return &failingCloseArchive{fakeArchive{w: w}}
+		})
+		t.Cleanup(func() {
+			registryMu.Lock()
+			defer registryMu.Unlock()
+			delete(registry, "failing")
+		})
+		path := filepath.Join(t.TempDir(), "archive.failing")
+		require.EqualError(t, CreateArchive(path, "failing", files), "could not close "+path+": fake close error")
+		require.NoFileExists(t, path)
+	})
+
+	t.Run("create error", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nope", "archive.tar")
+		require.ErrorIs(t, CreateArchive(path, "tar", files), fs.ErrNotExist)
+	})
+}
+
+func TestArchiveFormat(t *testing.T) {
+	for format, want := range map[string]string{
+		"tar":     "tar",
+		"tar.gz":  "tar.gz",
+		"tgz":     "tar.gz",
+		"tar.xz":  "tar.xz",
+		"txz":     "tar.xz",
+		"tar.zst": "tar.zst",
+		"tzst":    "tar.zst",
+		"zip":     "zip",
+		"gz":      "gz",
+		"ar":      "ar",
+		"cpio":    "cpio",
+	} {
+		t.Run(format, func(t *testing.T) {
+			archive, err := New(io.Discard, format)
+			require.NoError(t, err)
+			require.Equal(t, want, archive.Format())
+			require.NoError(t, archive.Close())
+		})
+	}
+
+	t.Run("registered", func(t *testing.T) {
+		Register("fake", func(w io.Writer) Archive {
+			return &fakeArchive{w: w}
+		})
+		t.Cleanup(func() {
+			registryMu.Lock()
+			defer registryMu.Unlock()
+			delete(registry, "fake")
+		})
+		archive, err := New(io.Discard, "fake")
+		require.NoError(t, err)
+		require.Equal(t, "fake", archive.Format())
+	})
+}
+
+func TestArchiveZstdOptions(t *testing.T) {
+	archive, err := New(io.Discard, "tar.zst", WithZstdOptions(tarzst.Options{Long: 20}))
+	require.NoError(t, err)
+	require.NoError(t, archive.Close())
+
+	_, err = New(io.Discard, "tar.zst", WithZstdOptions(tarzst.Options{Long: 40}))
+	require.ErrorContains(t, err, "zstd: window size must be at most")
+}
+
+func TestArchiveFlusher(t *testing.T) {
+	for format, want := range map[string]bool{
+		"tar":     true,
+		"tar.gz":  true,
+		"tar.zst": true,
+		"tar.xz":  false,
+		"zip":     false,
+		"gz":      false,
+	} {
+		t.Run(format, func(t *testing.T) {
+			archive, err := New(io.Discard, format)
+			require.NoError(t, err)
+			flusher, ok := archive.(Flusher)
+			require.Equal(t, want, ok)
+			if ok {
+				require.NoError(t, flusher.Flush())
+			}
+			require.NoError(t, archive.Close())
+		})
+	}
+}
+
+func TestOpenForAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.zip")
+	require.NoError(t, CreateArchive(path, "zip", []config.File{{
+		Source:      "testdata/foo.txt",
+		Destination: "foo.txt",
+	}}))
+
+	archive, err := OpenForAppend(path, "zip", WithPrefix("new"))
+	require.NoError(t, err)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "testdata/sub1/bar.txt",
+		Destination: "bar.txt",
+	}))
+	require.NoError(t, archive.Close())
+	require.Equal(t, []string{"foo.txt", "new/bar.txt"}, testlib.LsArchive(t, path, "zip"))
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := OpenForAppend(path, "tar")
+		require.EqualError(t, err, "append not supported for archive format: tar")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := OpenForAppend(filepath.Join(t.TempDir(), "nope.zip"), "zip")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("hasher", func(t *testing.T) {
+		h, err := NewHasher(crypto.SHA256)
+		require.NoError(t, err)
+		_, err = OpenForAppend(path, "zip", WithHasher(h))
+		require.EqualError(t, err, "append not supported with hashers or stats")
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		_, err := OpenForAppend(path, "zip", WithStats())
+		require.EqualError(t, err, "append not supported with hashers or stats")
+	})
+}
+
+func TestArchiveErrors(t *testing.T) {
+	foo := config.File{Source: "testdata/foo.txt", Destination: "foo.txt"}
+	formats := []string{"tar", "tar.gz", "tar.xz", "tar.zst", "zip", "gz", "ar", "cpio"}
+
+	for _, format := range formats {
+		t.Run(format+" closed", func(t *testing.T) {
+			archive, err := New(io.Discard, format)
+			require.NoError(t, err)
+			require.NoError(t, archive.Close())
+			require.ErrorIs(t, archive.Add(foo), ErrClosed)
+			require.ErrorIs(t, AddFS(archive, os.DirFS("testdata"), "fs"), ErrClosed)
+			require.ErrorIs(t, archive.Close(), ErrClosed)
+		})
+
+		if format == "gz" {
+			continue
+		}
+		t.Run(format+" duplicate", func(t *testing.T) {
+			archive, err := New(io.Discard, format)
+			require.NoError(t, err)
+			defer archive.Close()
+			require.NoError(t, archive.Add(foo))
+			require.ErrorIs(t, archive.Add(foo), ErrDuplicateEntry)
+		})
+	}
+
+	for _, format := range []string{"gz", "tar.xz", "txz", "tar.zst", "tzst", "ar", "cpio"} {
+		t.Run(format+" copy", func(t *testing.T) {
+			_, err := Copy(nil, io.Discard, format)
+			require.ErrorIs(t, err, ErrCopyUnsupported)
+		})
+	}
+
+	t.Run("registered copy", func(t *testing.T) {
+		Register("fake", func(w io.Writer) Archive {
+			return &fakeArchive{w: w}
+		})
+		t.Cleanup(func() {
+			registryMu.Lock()
+			defer registryMu.Unlock()
+			delete(registry, "fake")
+		})
+		_, err := Copy(nil, io.Discard, "fake")
+		require.EqualError(t, err, "copy not supported for archive format: fake")
+		require.ErrorIs(t, err, ErrCopyUnsupported)
+	})
+
+	t.Run("invalid format copy", func(t *testing.T) {
+		_, err := Copy(nil, io.Discard, "7z")
+		require.EqualError(t, err, "invalid archive format: 7z")
+		require.NotErrorIs(t, err, ErrCopyUnsupported)
+	})
+}
+
+func TestArchiveCopyBufferSize(t *testing.T) {
+	for _, format := range []string{"tar.gz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "archive."+format)
+			require.NoError(t, CreateArchive(path, format, []config.File{{
+				Source:      "testdata/foo.txt",
+				Destination: "foo.txt",
+			}}, WithCopyBufferSize(3)))
+			require.Equal(t, []string{"foo.txt"}, testlib.LsArchive(t, path, format))
+		})
+	}
+}
+
+func BenchmarkCopyBufferSize(b *testing.B) {
+	src := filepath.Join(b.TempDir(), "large.bin")
+	content := make([]byte, 64*1024*1024)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(content)
+	require.NoError(b, os.WriteFile(src, content, 0o644))
+
+	for _, size := range []int{0, 256 * 1024, 1024 * 1024} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			b.SetBytes(int64(len(content)))
+			for b.Loop() {
+				archive, err := New(io.Discard, "tar", WithCopyBufferSize(size))
+				require.NoError(b, err)
+				require.NoError(b, archive.Add(config.File{
+					Source:      src,
+					Destination: "large.bin",
+				}))
+				require.NoError(b, archive.Close())
+			}
+		})
+	}
+}
+
+func TestArchiveUnsafeSymlinks(t *testing.T) {
+	link := filepath.Join(t.TempDir(), "link")
+	require.NoError(t, os.Symlink("/etc/passwd", link))
+
+	archive, err := New(io.Discard, "tar.gz", WithRejectUnsafeSymlinks())
+	require.NoError(t, err)
+	require.ErrorIs(t, archive.Add(config.File{Source: link, Destination: "link"}), destination.ErrUnsafe)
+	require.NoError(t, archive.Close())
+
+	path := filepath.Join(t.TempDir(), "archive.tar.gz")
+	require.NoError(t, CreateArchive(path, "tar.gz", []config.File{{
+		Source:      link,
+		Destination: "link",
+	}}, WithRejectUnsafeSymlinks(), WithRewriteUnsafeSymlinks()))
+	require.Equal(t, []string{"link"}, testlib.LsArchive(t, path, "tar.gz"))
+}
+
+func TestArchiveThreadsWithoutXz(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.tar.xz"))
+	require.NoError(t, err)
+	archive, err := New(f, "tar.xz", WithThreads(4))
+	require.NoError(t, err)
+	require.NoError(t, archive.Add(config.File{Source: "testdata/foo.txt", Destination: "foo.txt"}))
+	require.NoError(t, archive.Close())
+	require.NoError(t, f.Close())
+	require.Equal(t, []string{"foo.txt"}, testlib.LsArchive(t, f.Name(), "tar.xz"))
+}
+
+func TestArchiveRecordSize(t *testing.T) {
+	var buf bytes.Buffer
+	archive, err := New(&buf, "tar.gz", WithRecordSize(10240))
+	require.NoError(t, err)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	require.NoError(t, archive.Close())
+
+	gr, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	bts, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	require.Len(t, bts, 10240)
+}
+
+func TestNewWithCommonOptions(t *testing.T) {
+	text := filepath.Join(t.TempDir(), "text.txt")
+	var content strings.Builder
+	for i := range 20_000 {
+		fmt.Fprintf(&content, "line %d of some text\n", i%1000)
+	}
+	require.NoError(t, os.WriteFile(text, []byte(content.String()), 0o644))
+
+	build := func(tb testing.TB, format string, o Options, files ...config.File) []byte {
+		tb.Helper()
+		var buf bytes.Buffer
+		archive, err := NewWithCommonOptions(&buf, format, o)
+		require.NoError(tb, err)
+		for _, f := range files {
+			require.NoError(tb, archive.Add(f))
+		}
+		require.NoError(tb, archive.Close())
+		return buf.Bytes()
+	}
+	file := config.File{Source: text, Destination: "text.txt"}
+
+	for _, format := range []string{"tar.gz", "tar.zst", "zip"} {
+		t.Run(format+" level", func(t *testing.T) {
+			fast := build(t, format, Options{Level: 1}, file)
+			best := build(t, format, Options{}, file)
+			require.Less(t, len(best), len(fast))
+		})
+	}
+
+	t.Run("invalid level", func(t *testing.T) {
+		_, err := NewWithCommonOptions(io.Discard, "tar.gz", Options{Level: 42})
+		require.Error(t, err)
+	})
+
+	t.Run("reproducible", func(t *testing.T) {
+		bts := build(t, "zip", Options{Reproducible: true},
+			config.File{Source: "testdata/sub1/bar.txt", Destination: "b.txt"},
+			config.File{Source: "testdata/foo.txt", Destination: "a.txt"},
+		)
+		r, err := stdzip.NewReader(bytes.NewReader(bts), int64(len(bts)))
+		require.NoError(t, err)
+		require.Equal(t, "a.txt", r.File[0].Name)
+		require.Equal(t, "b.txt", r.File[1].Name)
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "archive.tar.gz")
+		require.NoError(t, os.WriteFile(path, build(t, "tar.gz", Options{Prefix: "app"}, file), 0o644))
+		require.Equal(t, []string{"app/text.txt"}, testlib.LsArchive(t, path, "tar.gz"))
+	})
+
+	t.Run("clamp mtime", func(t *testing.T) {
+		clamp := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		bts := build(t, "zip", Options{ClampMTime: clamp}, file)
+		r, err := stdzip.NewReader(bytes.NewReader(bts), int64(len(bts)))
+		require.NoError(t, err)
+		require.True(t, clamp.Equal(r.File[0].Modified), r.File[0].Modified)
+	})
+}
@@ -0,0 +1,35 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BestAvailableFormat returns the first of the given formats which can
+// actually be used, so builds can degrade gracefully when, e.g., a registered
+// format depends on something missing in the system.
+//
+// Each format is probed by writing an empty archive with it.
+func BestAvailableFormat(preferred []string) (string, error) {
+	if len(preferred) == 0 {
+		return "", errors.New("no archive formats to choose from")
+	}
+	var errs []error
+	for _, format := range preferred {
+		if err := probe(format); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", format, err))
+			continue
+		}
+		return format, nil
+	}
+	return "", fmt.Errorf("no archive format available: %w", errors.Join(errs...))
+}
+
+func probe(format string) error {
+	a, err := New(io.Discard, format)
+	if err != nil {
+		return err
+	}
+	return a.Close()
+}
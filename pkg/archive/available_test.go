@@ -0,0 +1,59 @@
+package archive
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+var errUnavailable = errors.New("compressor not available")
+
+// unavailableArchive fails like a format whose compressor is missing.
+type unavailableArchive struct{}
+
+func (unavailableArchive) Close() error          { return errUnavailable }
+func (unavailableArchive) Add(config.File) error { return errUnavailable }
+func (unavailableArchive) Format() string        { return "unavailable" }
+
+func TestBestAvailableFormat(t *testing.T) {
+	Register("unavailable", func(io.Writer) Archive {
+		return unavailableArchive{}
+	})
+	Register("fake", func(w io.Writer) Archive {
+		return &fakeArchive{w: w}
+	})
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		delete(registry, "unavailable")
+		delete(registry, "fake")
+	})
+
+	for _, tt := range []struct {
+		preferred []string
+		expected  string
+	}{
+		{[]string{"tar.zst", "tar.xz", "tar.gz"}, "tar.zst"},
+		{[]string{"unavailable", "tar.xz", "tar.gz"}, "tar.xz"},
+		{[]string{"7z", "unavailable", "fake", "zip"}, "fake"},
+		{[]string{"unavailable", "zip"}, "zip"},
+	} {
+		got, err := BestAvailableFormat(tt.preferred)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, got)
+	}
+
+	t.Run("none available", func(t *testing.T) {
+		_, err := BestAvailableFormat([]string{"unavailable", "7z"})
+		require.ErrorIs(t, err, errUnavailable)
+		require.EqualError(t, err, "no archive format available: unavailable: compressor not available\n7z: invalid archive format: 7z")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := BestAvailableFormat(nil)
+		require.EqualError(t, err, "no archive formats to choose from")
+	})
+}
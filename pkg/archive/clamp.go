@@ -0,0 +1,58 @@
+package archive
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// WithClampMTime makes the modification times of all entries be at most the
+// given time, like SOURCE_DATE_EPOCH does, so files changed by the build
+// itself don't make the archive differ.
+func WithClampMTime(mtime time.Time) Option {
+	return func(o *options) {
+		o.clampMTime = mtime
+	}
+}
+
+type clampArchive struct {
+	Archive
+	mtime time.Time
+}
+
+func (a clampArchive) unwrap() Archive { return a.Archive }
+
+func (a clampArchive) Add(f config.File) error {
+	var src fs.FileInfo
+	if f.Source != "" {
+		if info, err := os.Lstat(f.Source); err == nil {
+			src = info
+		}
+	}
+	return a.Archive.Add(a.clamp(f, src))
+}
+
+func (a clampArchive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	return addReader(a.Archive, a.clamp(f, info), info, r)
+}
+
+// clamp clamps the modification time of the given file, which defaults to
+// the one of its source, if known.
+func (a clampArchive) clamp(f config.File, src fs.FileInfo) config.File {
+	mtime := f.Info.ParsedMTime
+	if mtime.IsZero() {
+		if src != nil {
+			mtime = src.ModTime()
+		} else if f.Source == "" {
+			// explicit directories default to the current time.
+			mtime = time.Now()
+		}
+	}
+	if mtime.After(a.mtime) {
+		f.Info.ParsedMTime = a.mtime
+	}
+	return f
+}
@@ -0,0 +1,73 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClampMTime(t *testing.T) {
+	clamp := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	oldFile := filepath.Join(t.TempDir(), "old.txt")
+	require.NoError(t, os.WriteFile(oldFile, []byte("old"), 0o644))
+	require.NoError(t, os.Chtimes(oldFile, old, old))
+
+	var buf bytes.Buffer
+	archive, err := New(&buf, "tar", WithClampMTime(clamp))
+	require.NoError(t, err)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "testdata/foo.txt",
+		Destination: "foo.txt",
+		Info:        config.FileInfo{ParsedMTime: recent},
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      oldFile,
+		Destination: "old.txt",
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      oldFile,
+		Destination: "explicit.txt",
+		Info:        config.FileInfo{ParsedMTime: old.Add(time.Hour)},
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Destination: "dir",
+		Info:        config.FileInfo{Mode: fs.ModeDir | 0o755},
+	}))
+	require.NoError(t, AddFS(archive, fstest.MapFS{
+		"recent.txt": {Data: []byte("recent"), ModTime: recent},
+		"old.txt":    {Data: []byte("old"), ModTime: old},
+	}, "fs"))
+	require.NoError(t, archive.Close())
+
+	mtimes := map[string]time.Time{}
+	r := tar.NewReader(&buf)
+	for {
+		header, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		mtimes[header.Name] = header.ModTime.UTC()
+	}
+	require.Equal(t, map[string]time.Time{
+		"foo.txt":       clamp,
+		"old.txt":       old,
+		"explicit.txt":  old.Add(time.Hour),
+		"dir/":          clamp,
+		"fs/old.txt":    old,
+		"fs/recent.txt": clamp,
+	}, mtimes)
+}
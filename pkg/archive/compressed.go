@@ -0,0 +1,128 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/klauspost/compress/zstd"
+	gzip "github.com/klauspost/pgzip"
+	"github.com/ulikunitz/xz"
+)
+
+// compressors are the algorithms supported by [AddCompressed], by name, with
+// the extension they add to the destination.
+var compressors = map[string]struct {
+	ext    string
+	writer func(w io.Writer, name string, info os.FileInfo) (io.WriteCloser, error)
+}{
+	"gz": {".gz", func(w io.Writer, name string, info os.FileInfo) (io.WriteCloser, error) {
+		gw, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		gw.Name = name
+		gw.ModTime = info.ModTime()
+		return gw, nil
+	}},
+	"xz": {".xz", func(w io.Writer, _ string, _ os.FileInfo) (io.WriteCloser, error) {
+		return xz.NewWriter(w)
+	}},
+	"zst": {".zst", func(w io.Writer, _ string, _ os.FileInfo) (io.WriteCloser, error) {
+		return zstd.NewWriter(w)
+	}},
+}
+
+// AddCompressed adds the given file to the archive compressed with the given
+// algorithm, "gz", "xz" or "zst", appending its extension to the destination,
+// e.g. a man page added as "man/foo.1" with "gz" is added as "man/foo.1.gz".
+//
+// The source is compressed into a temporary file, as archives need to know
+// the size of their entries up front, which is then added to the archive.
+// Zip archives remove it when closed, as reproducible ones only read their
+// sources then; all other archives remove it right away.
+// Unless set in the file info, the mode and modification time of the entry
+// are the ones of the source.
+func AddCompressed(a Archive, f config.File, algo string) error {
+	c, ok := compressors[algo]
+	if !ok {
+		return fmt.Errorf("%s: invalid compression algorithm: %s", f.Destination, algo)
+	}
+	src, err := os.Open(f.Source) // #nosec
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Source, err)
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Source, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s: not a regular file: %s", f.Source, info.Mode().Type())
+	}
+
+	tmp, err := os.CreateTemp("", "goreleaser-compressed-*")
+	if err != nil {
+		return err
+	}
+	if err := compressTo(tmp, src, c.writer, path.Base(f.Destination), info); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("%s: %w", f.Source, err)
+	}
+
+	f.Source = tmp.Name()
+	f.Destination += c.ext
+	if f.Info.Mode == 0 {
+		f.Info.Mode = info.Mode().Perm()
+	}
+	if f.Info.ParsedMTime.IsZero() {
+		f.Info.ParsedMTime = info.ModTime()
+	}
+	if err := a.Add(f); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	afterClose(a, func() { _ = os.Remove(tmp.Name()) })
+	return nil
+}
+
+func compressTo(
+	tmp *os.File,
+	src io.Reader,
+	writer func(w io.Writer, name string, info os.FileInfo) (io.WriteCloser, error),
+	name string,
+	info os.FileInfo,
+) error {
+	defer tmp.Close()
+	w, err := writer(tmp, name, info)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		_ = w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return tmp.Close()
+}
+
+// afterClose calls fn once the given archive is closed, if it, or any archive
+// it wraps, only reads its sources then, or right away otherwise.
+func afterClose(a Archive, fn func()) {
+	for a != nil {
+		if ac, ok := a.(interface{ AfterClose(fn func()) }); ok {
+			ac.AfterClose(fn)
+			return
+		}
+		u, ok := a.(interface{ unwrap() Archive })
+		if !ok {
+			break
+		}
+		a = u.unwrap()
+	}
+	fn()
+}
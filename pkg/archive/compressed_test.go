@@ -0,0 +1,120 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+func TestAddCompressed(t *testing.T) {
+	content, err := os.ReadFile("testdata/foo.txt")
+	require.NoError(t, err)
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	src := filepath.Join(t.TempDir(), "foo.1")
+	require.NoError(t, os.WriteFile(src, content, 0o644))
+	require.NoError(t, os.Chtimes(src, mtime, mtime))
+
+	for algo, decompress := range map[string]func(r io.Reader) (io.Reader, error){
+		"gz": func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+		"xz": func(r io.Reader) (io.Reader, error) {
+			return xz.NewReader(r)
+		},
+		"zst": func(r io.Reader) (io.Reader, error) {
+			return zstd.NewReader(r)
+		},
+	} {
+		t.Run(algo, func(t *testing.T) {
+			var buf bytes.Buffer
+			archive, err := New(&buf, "tar")
+			require.NoError(t, err)
+			require.NoError(t, AddCompressed(archive, config.File{
+				Source:      src,
+				Destination: "man/foo.1",
+			}, algo))
+			require.NoError(t, archive.Close())
+
+			r := tar.NewReader(&buf)
+			header, err := r.Next()
+			require.NoError(t, err)
+			require.Equal(t, "man/foo.1."+algo, header.Name)
+			require.Equal(t, int64(0o644), header.Mode)
+			require.True(t, mtime.Equal(header.ModTime))
+
+			dr, err := decompress(r)
+			require.NoError(t, err)
+			got, err := io.ReadAll(dr)
+			require.NoError(t, err)
+			require.Equal(t, content, got)
+		})
+	}
+
+	for name, newArchive := range map[string]func(w io.Writer) (Archive, error){
+		"reproducible zip": func(w io.Writer) (Archive, error) {
+			return New(w, "zip", WithReproducible())
+		},
+		"concurrent zip": func(w io.Writer) (Archive, error) {
+			return NewConcurrent(w, "zip")
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// the temporary file must be there until the archive is closed.
+			tmp := t.TempDir()
+			t.Setenv("TMPDIR", tmp)
+			var buf bytes.Buffer
+			archive, err := newArchive(&buf)
+			require.NoError(t, err)
+			require.NoError(t, AddCompressed(archive, config.File{
+				Source:      src,
+				Destination: "man/foo.1",
+			}, "gz"))
+			require.NoError(t, archive.Close())
+			entries, err := os.ReadDir(tmp)
+			require.NoError(t, err)
+			require.Empty(t, entries)
+
+			r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			require.NoError(t, err)
+			require.Len(t, r.File, 1)
+			require.Equal(t, "man/foo.1.gz", r.File[0].Name)
+			f, err := r.File[0].Open()
+			require.NoError(t, err)
+			defer f.Close()
+			gr, err := gzip.NewReader(f)
+			require.NoError(t, err)
+			got, err := io.ReadAll(gr)
+			require.NoError(t, err)
+			require.Equal(t, content, got)
+		})
+	}
+
+	t.Run("invalid algorithm", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar")
+		require.NoError(t, err)
+		require.EqualError(t, AddCompressed(archive, config.File{
+			Source:      src,
+			Destination: "foo.1",
+		}, "bz2"), "foo.1: invalid compression algorithm: bz2")
+	})
+
+	t.Run("missing source", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar")
+		require.NoError(t, err)
+		require.ErrorIs(t, AddCompressed(archive, config.File{
+			Source:      "testdata/nope.txt",
+			Destination: "nope.txt",
+		}, "gz"), os.ErrNotExist)
+	})
+}
@@ -0,0 +1,98 @@
+package archive
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"sync"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/closed"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/destination"
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// NewConcurrent creates an archive in the given format which can be added to
+// from multiple goroutines.
+//
+// Added files are buffered, and only written on Close, sorted by destination,
+// so the output is the same regardless of the order they were added in.
+// Files added from readers, e.g. with [AddFS], are copied into temporary
+// files until then.
+// With [WithStats], it implements [StatsReporter] too.
+func NewConcurrent(w io.Writer, format string, opts ...Option) (Archive, error) {
+	a, err := New(w, format, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return forwardStats(&concurrent{
+		a:      a,
+		files:  map[string]bool{},
+		closed: &closed.Flag{},
+	}, a), nil
+}
+
+type concurrent struct {
+	a Archive
+
+	mu         sync.Mutex
+	closed     *closed.Flag
+	files      map[string]bool
+	entries    []config.File
+	afterClose []func()
+}
+
+func (c *concurrent) Format() string {
+	return c.a.Format()
+}
+
+// Add buffers the given file, which is written on Close.
+func (c *concurrent) Add(f config.File) error {
+	if err := destination.Validate(f.Destination); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.closed.Check(f.Destination); err != nil {
+		return err
+	}
+	if c.files[f.Destination] {
+		return &fs.PathError{Err: fs.ErrExist, Path: f.Destination, Op: "add"}
+	}
+	c.files[f.Destination] = true
+	c.entries = append(c.entries, f)
+	return nil
+}
+
+// AfterClose makes the given function be called once the archive is closed,
+// as the buffered files are only read then.
+func (c *concurrent) AfterClose(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.afterClose = append(c.afterClose, fn)
+}
+
+// Close writes all the buffered files, and closes the archive.
+func (c *concurrent) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.closed.Close(); err != nil {
+		return err
+	}
+	defer func() {
+		for _, fn := range c.afterClose {
+			fn()
+		}
+	}()
+	slices.SortFunc(c.entries, func(a, b config.File) int {
+		return cmp.Compare(a.Destination, b.Destination)
+	})
+	for _, f := range c.entries {
+		if err := c.a.Add(f); err != nil {
+			_ = c.a.Close()
+			return fmt.Errorf("could not add %s: %w", f.Source, err)
+		}
+	}
+	return c.a.Close()
+}
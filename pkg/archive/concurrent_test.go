@@ -0,0 +1,124 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	mtime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var files []config.File
+	for i := range 100 {
+		src := filepath.Join(dir, fmt.Sprintf("file%03d.txt", i))
+		require.NoError(t, os.WriteFile(src, []byte(fmt.Sprintf("content %d\n", i)), 0o644))
+		files = append(files, config.File{
+			Source:      src,
+			Destination: fmt.Sprintf("dir%d/file%03d.txt", i%3, i),
+			Info: config.FileInfo{
+				Mode:        0o644,
+				ParsedMTime: mtime,
+			},
+		})
+	}
+
+	build := func(tb testing.TB, files []config.File) []byte {
+		tb.Helper()
+		var buf bytes.Buffer
+		archive, err := NewConcurrent(&buf, "tar.gz")
+		require.NoError(tb, err)
+		require.Equal(tb, "tar.gz", archive.Format())
+		var wg sync.WaitGroup
+		errs := make(chan error, len(files))
+		for _, f := range files {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- archive.Add(f)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(tb, err)
+		}
+		require.NoError(tb, AddFS(archive, fstest.MapFS{
+			"fs.txt": {Data: []byte("fs"), Mode: 0o644, ModTime: mtime},
+		}, "zzz"))
+		require.NoError(tb, archive.Close())
+		return buf.Bytes()
+	}
+
+	first := build(t, files)
+	reversed := slices.Clone(files)
+	slices.Reverse(reversed)
+	require.Equal(t, first, build(t, reversed))
+
+	gzf, err := gzip.NewReader(bytes.NewReader(first))
+	require.NoError(t, err)
+	r := tar.NewReader(gzf)
+	var names []string
+	for {
+		next, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, next.Name)
+	}
+	require.Len(t, names, len(files)+1)
+	require.True(t, slices.IsSorted(names[:len(files)]))
+	require.Equal(t, "zzz/fs.txt", names[len(files)])
+
+	t.Run("duplicate", func(t *testing.T) {
+		archive, err := NewConcurrent(io.Discard, "tar")
+		require.NoError(t, err)
+		require.NoError(t, archive.Add(files[0]))
+		require.ErrorIs(t, archive.Add(files[0]), fs.ErrExist)
+		require.NoError(t, archive.Close())
+	})
+
+	t.Run("add error", func(t *testing.T) {
+		archive, err := NewConcurrent(io.Discard, "tar")
+		require.NoError(t, err)
+		for _, f := range files {
+			require.NoError(t, archive.Add(f))
+		}
+		require.NoError(t, archive.Add(config.File{
+			Source:      "testdata/nope.txt",
+			Destination: "nope.txt",
+		}))
+		err = archive.Close()
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		require.ErrorContains(t, err, "could not add testdata/nope.txt")
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		archive, err := NewConcurrent(io.Discard, "tar")
+		require.NoError(t, err)
+		require.NoError(t, archive.Close())
+		require.ErrorIs(t, archive.Add(files[0]), ErrClosed)
+		require.ErrorIs(t, AddFS(archive, fstest.MapFS{"a": {}}, "fs"), ErrClosed)
+		require.ErrorIs(t, archive.Close(), ErrClosed)
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		_, err := NewConcurrent(io.Discard, "7z")
+		require.EqualError(t, err, "invalid archive format: 7z")
+	})
+}
@@ -0,0 +1,53 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"hash"
+	"io"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/counting"
+)
+
+// CountingWriter wraps the writer of an archive, counting the bytes written
+// to it and computing their SHA256 checksum, so both are known as soon as the
+// archive is closed, without reading it again.
+type CountingWriter struct {
+	w counting.Writer
+	h hash.Hash
+}
+
+// NewCountingWriter creates a [CountingWriter] writing to w.
+func NewCountingWriter(w io.Writer) *CountingWriter {
+	return &CountingWriter{
+		w: counting.Writer{W: w},
+		h: sha256.New(),
+	}
+}
+
+// Write writes p to the underlying writer, counting and hashing what was
+// actually written.
+func (c *CountingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	// hashes never return errors.
+	_, _ = c.h.Write(p[:n])
+	return n, err
+}
+
+// Count returns the number of bytes written so far.
+func (c *CountingWriter) Count() int64 {
+	return c.w.N
+}
+
+// Sum returns the SHA256 checksum of everything written so far.
+func (c *CountingWriter) Sum() []byte {
+	return c.h.Sum(nil)
+}
+
+// CloseArchive closes the given archive, which must write to c, and returns
+// its size and SHA256 checksum.
+func (c *CountingWriter) CloseArchive(a Archive) (int64, []byte, error) {
+	if err := a.Close(); err != nil {
+		return 0, nil, err
+	}
+	return c.w.N, c.Sum(), nil
+}
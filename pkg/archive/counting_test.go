@@ -0,0 +1,67 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountingWriter(t *testing.T) {
+	for _, format := range []string{"tar", "tar.gz", "tar.xz", "tar.zst", "zip", "gz", "ar", "cpio"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewCountingWriter(&buf)
+			archive, err := New(w, format)
+			require.NoError(t, err)
+			require.NoError(t, archive.Add(config.File{
+				Source:      "testdata/foo.txt",
+				Destination: "foo.txt",
+			}))
+			n, sum, err := w.CloseArchive(archive)
+			require.NoError(t, err)
+
+			require.Equal(t, int64(buf.Len()), n)
+			require.Equal(t, n, w.Count())
+			expected := sha256.Sum256(buf.Bytes())
+			require.Equal(t, expected[:], sum)
+			require.Equal(t, sum, w.Sum())
+		})
+	}
+
+	t.Run("close error", func(t *testing.T) {
+		w := NewCountingWriter(&bytes.Buffer{})
+		archive, err := New(w, "tar")
+		require.NoError(t, err)
+		require.NoError(t, archive.Close())
+		_, _, err = w.CloseArchive(archive)
+		require.ErrorIs(t, err, ErrClosed)
+	})
+
+	t.Run("short write", func(t *testing.T) {
+		w := NewCountingWriter(shortWriter{limit: 3})
+		n, err := w.Write([]byte("foobar"))
+		require.ErrorIs(t, err, errShortWrite)
+		require.Equal(t, 3, n)
+		require.Equal(t, int64(3), w.Count())
+		expected := sha256.Sum256([]byte("foo"))
+		require.Equal(t, expected[:], w.Sum())
+	})
+}
+
+var errShortWrite = errors.New("short write")
+
+// shortWriter only accepts the first limit bytes of each write.
+type shortWriter struct {
+	limit int
+}
+
+func (w shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		return w.limit, errShortWrite
+	}
+	return len(p), nil
+}
@@ -0,0 +1,258 @@
+// Package cpio implements the Archive interface providing cpio archiving, in
+// the newc format, as used by initramfs images.
+package cpio
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/closed"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/destination"
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+const (
+	// magic identifies the newc format, with no checksums.
+	magic = "070701"
+
+	// trailer is the name of the last entry of every cpio archive.
+	trailer = "TRAILER!!!"
+
+	// file type bits of the entry modes.
+	typeDir     = 0o040000
+	typeReg     = 0o100000
+	typeSymlink = 0o120000
+
+	// maxField is the largest value of the numeric header fields, which are
+	// 8 hexadecimal digits long.
+	maxField = 0xffffffff
+)
+
+// Archive as cpio.
+//
+// Owners and groups can only be stored as numeric IDs, so they are only kept
+// if numeric, and set to 0 otherwise.
+type Archive struct {
+	w      io.Writer
+	files  map[string]bool
+	closed *closed.Flag
+}
+
+// New cpio archive.
+func New(target io.Writer) Archive {
+	return Archive{
+		w:      target,
+		files:  map[string]bool{},
+		closed: &closed.Flag{},
+	}
+}
+
+// Format returns the archive format, "cpio".
+func (a Archive) Format() string {
+	return "cpio"
+}
+
+// Close writes the trailer entry.
+func (a Archive) Close() error {
+	if err := a.closed.Close(); err != nil {
+		return err
+	}
+	return a.writeHeader(header{name: trailer, nlink: 1}, 0)
+}
+
+// Add file to the archive.
+func (a Archive) Add(f config.File) error {
+	// entry names always use forward slashes, even when built on Windows.
+	f.Destination = filepath.ToSlash(f.Destination)
+	if err := a.closed.Check(f.Destination); err != nil {
+		return err
+	}
+	if err := a.register(f.Destination); err != nil {
+		return err
+	}
+	h := header{
+		name:  strings.TrimSuffix(f.Destination, "/"),
+		ino:   len(a.files),
+		nlink: 1,
+		uid:   id(f.Info.Owner),
+		gid:   id(f.Info.Group),
+		mtime: f.Info.ParsedMTime,
+	}
+	if f.Source == "" && f.Info.Mode.IsDir() {
+		h.mode = typeDir | uint32(f.Info.Mode.Perm())
+		h.nlink = 2
+		if h.mtime.IsZero() {
+			h.mtime = time.Now()
+		}
+		return a.writeHeader(h, 0)
+	}
+	info, err := os.Lstat(f.Source) // #nosec
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Source, err)
+	}
+	if h.mtime.IsZero() {
+		h.mtime = info.ModTime()
+	}
+	perm := info.Mode().Perm()
+	if f.Info.Mode != 0 {
+		perm = f.Info.Mode.Perm()
+	}
+	switch {
+	case info.IsDir():
+		h.mode = typeDir | uint32(perm)
+		h.nlink = 2
+		return a.writeHeader(h, 0)
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(f.Source) // #nosec
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Source, err)
+		}
+		h.mode = typeSymlink | uint32(perm)
+		h.size = int64(len(link))
+		return a.write(h, strings.NewReader(link))
+	case info.Mode().IsRegular():
+		file, err := os.Open(f.Source) // #nosec
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Source, err)
+		}
+		defer file.Close()
+		h.mode = typeReg | uint32(perm)
+		h.size = info.Size()
+		return a.write(h, file)
+	}
+	return fmt.Errorf("%s: unsupported file type: %s", f.Source, info.Mode().Type())
+}
+
+// AddWithReader adds a regular file to the archive, reading its content from
+// r, with the size, mode and modification time of the given info, unless set
+// in the file info.
+func (a Archive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	// entry names always use forward slashes, even when built on Windows.
+	f.Destination = filepath.ToSlash(f.Destination)
+	if err := a.closed.Check(f.Destination); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s: not a regular file: %s", f.Destination, info.Mode().Type())
+	}
+	if err := a.register(f.Destination); err != nil {
+		return err
+	}
+	h := header{
+		name:  f.Destination,
+		ino:   len(a.files),
+		mode:  typeReg | uint32(info.Mode().Perm()),
+		uid:   id(f.Info.Owner),
+		gid:   id(f.Info.Group),
+		nlink: 1,
+		mtime: f.Info.ParsedMTime,
+		size:  info.Size(),
+	}
+	if f.Info.Mode != 0 {
+		h.mode = typeReg | uint32(f.Info.Mode.Perm())
+	}
+	if h.mtime.IsZero() {
+		h.mtime = info.ModTime()
+	}
+	return a.write(h, r)
+}
+
+// register validates the given destination, and marks it as added.
+func (a Archive) register(dst string) error {
+	if err := destination.Validate(dst); err != nil {
+		return err
+	}
+	dst = strings.TrimSuffix(dst, "/")
+	if _, ok := a.files[dst]; ok {
+		return &fs.PathError{Err: fs.ErrExist, Path: dst, Op: "add"}
+	}
+	a.files[dst] = true
+	return nil
+}
+
+type header struct {
+	name     string
+	ino      int
+	mode     uint32
+	uid, gid int
+	nlink    int
+	mtime    time.Time
+	size     int64
+}
+
+// write writes the given header, followed by the content of r, which must
+// be exactly h.size bytes long.
+func (a Archive) write(h header, r io.Reader) error {
+	if err := a.writeHeader(h, h.size); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(a.w, r, h.size); err != nil {
+		return fmt.Errorf("%s: %w", h.name, err)
+	}
+	return a.pad(h.name, h.size)
+}
+
+func (a Archive) writeHeader(h header, size int64) error {
+	var mtime int64
+	if !h.mtime.IsZero() {
+		mtime = h.mtime.Unix()
+	}
+	if size > maxField {
+		return fmt.Errorf("%s: file too large for cpio newc: %d bytes", h.name, size)
+	}
+	for _, field := range []struct {
+		name  string
+		value int64
+	}{
+		{"inode", int64(h.ino)},
+		{"uid", int64(h.uid)},
+		{"gid", int64(h.gid)},
+		{"nlink", int64(h.nlink)},
+		{"mtime", mtime},
+		{"size", size},
+	} {
+		if field.value < 0 || field.value > maxField {
+			return fmt.Errorf("%s: %s out of range for cpio newc: %d", h.name, field.name, field.value)
+		}
+	}
+	// the header is made of the magic, followed by 13 fields, formatted as
+	// 8 hexadecimal digits each: inode, mode, uid, gid, nlink, mtime, size,
+	// device major and minor, rdevice major and minor, name size, and
+	// checksum.
+	raw := fmt.Sprintf(
+		"%s%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%s\x00",
+		magic, h.ino, h.mode, h.uid, h.gid, h.nlink, mtime, size,
+		0, 0, 0, 0, len(h.name)+1, 0, h.name,
+	)
+	if _, err := io.WriteString(a.w, raw); err != nil {
+		return fmt.Errorf("%s: %w", h.name, err)
+	}
+	return a.pad(h.name, int64(len(raw)))
+}
+
+// pad aligns the output to 4 bytes, assuming n bytes were written since the
+// last aligned offset.
+func (a Archive) pad(name string, n int64) error {
+	if rem := n % 4; rem != 0 {
+		if _, err := a.w.Write(make([]byte, 4-rem)); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// id returns the given owner or group as a numeric ID, or 0 if it is not
+// numeric.
+func id(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,181 @@
+package cpio
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+type entry struct {
+	name  string
+	mode  uint32
+	uid   int64
+	gid   int64
+	mtime int64
+	data  string
+}
+
+// readEntries parses the given newc cpio archive, up to its trailer.
+func readEntries(tb testing.TB, bts []byte) []entry {
+	tb.Helper()
+	align := func(n int) int { return (n + 3) &^ 3 }
+	var entries []entry
+	for {
+		require.GreaterOrEqual(tb, len(bts), 110)
+		require.Equal(tb, magic, string(bts[:6]))
+		field := func(i int) int64 {
+			n, err := strconv.ParseInt(string(bts[6+i*8:14+i*8]), 16, 64)
+			require.NoError(tb, err)
+			return n
+		}
+		size := int(field(6))
+		nameSize := int(field(11))
+		name := string(bts[110 : 110+nameSize-1])
+		require.Equal(tb, byte(0), bts[110+nameSize-1])
+		data := align(110 + nameSize)
+		if name == trailer {
+			require.Equal(tb, 0, size)
+			require.Len(tb, bts, data)
+			return entries
+		}
+		entries = append(entries, entry{
+			name:  name,
+			mode:  uint32(field(1)),
+			uid:   field(2),
+			gid:   field(3),
+			mtime: field(5),
+			data:  string(bts[data : data+size]),
+		})
+		bts = bts[align(data+size):]
+	}
+}
+
+func TestCpioFile(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	var buf bytes.Buffer
+	archive := New(&buf)
+	require.Equal(t, "cpio", archive.Format())
+
+	require.Error(t, archive.Add(config.File{
+		Source:      "../testdata/nope.txt",
+		Destination: "nope.txt",
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Destination: "etc",
+		Info: config.FileInfo{
+			Mode:        os.ModeDir | 0o755,
+			ParsedMTime: now,
+		},
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "etc/foo.txt",
+		Info: config.FileInfo{
+			Mode:        0o600,
+			Owner:       "1000",
+			Group:       "100",
+			ParsedMTime: now,
+		},
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/sub1/bar.txt",
+		Destination: "bar.txt",
+		Info: config.FileInfo{
+			Mode:        0o644,
+			Owner:       "carlos",
+			Group:       "root",
+			ParsedMTime: now,
+		},
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/link.txt",
+		Destination: "link.txt",
+		Info: config.FileInfo{
+			ParsedMTime: now,
+		},
+	}))
+	require.ErrorIs(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "etc/foo.txt",
+	}), fs.ErrExist)
+	require.Error(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "../foo.txt",
+	}))
+	info, err := fs.Stat(fstest.MapFS{"fs.txt": {Data: []byte("hello"), Mode: 0o644, ModTime: now}}, "fs.txt")
+	require.NoError(t, err)
+	require.NoError(t, archive.AddWithReader(config.File{Destination: "sub/fs.txt"}, info, strings.NewReader("hello")))
+	require.NoError(t, archive.Close())
+
+	require.Equal(t, []entry{
+		{name: "etc", mode: typeDir | 0o755, mtime: now.Unix()},
+		{name: "etc/foo.txt", mode: typeReg | 0o600, uid: 1000, gid: 100, mtime: now.Unix(), data: "foo\n"},
+		{name: "bar.txt", mode: typeReg | 0o644, mtime: now.Unix(), data: "bar\n"},
+		{name: "link.txt", mode: typeSymlink | 0o777, mtime: now.Unix(), data: "regular.txt"},
+		{name: "sub/fs.txt", mode: typeReg | 0o644, mtime: now.Unix(), data: "hello"},
+	}, readEntries(t, buf.Bytes()))
+
+	if _, err := exec.LookPath("cpio"); err != nil {
+		return
+	}
+	dir := t.TempDir()
+	cmd := exec.Command("cpio", "-i", "-d", "--quiet")
+	cmd.Dir = dir
+	cmd.Stdin = &buf
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	bts, err := os.ReadFile(filepath.Join(dir, "etc", "foo.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "foo\n", string(bts))
+	link, err := os.Readlink(filepath.Join(dir, "link.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "regular.txt", link)
+	bts, err = os.ReadFile(filepath.Join(dir, "sub", "fs.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(bts))
+}
+
+func TestCpioEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	archive := New(&buf)
+	require.NoError(t, archive.Close())
+	require.Empty(t, readEntries(t, buf.Bytes()))
+}
+
+func TestCpioOutOfRange(t *testing.T) {
+	info, err := fs.Stat(fstest.MapFS{"big.bin": {Mode: 0o644}}, "big.bin")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	archive := New(&buf)
+	// the content is never read, as the header is rejected first.
+	require.EqualError(t, archive.AddWithReader(config.File{Destination: "big.bin"}, sizedInfo{info, 1 << 32}, strings.NewReader("")),
+		"big.bin: file too large for cpio newc: 4294967296 bytes")
+	require.EqualError(t, archive.AddWithReader(config.File{
+		Destination: "owned.bin",
+		Info:        config.FileInfo{Owner: "4294967296"},
+	}, info, strings.NewReader("")), "owned.bin: uid out of range for cpio newc: 4294967296")
+	require.EqualError(t, archive.AddWithReader(config.File{
+		Destination: "old.bin",
+		Info:        config.FileInfo{ParsedMTime: time.Unix(-1, 0)},
+	}, info, strings.NewReader("")), "old.bin: mtime out of range for cpio newc: -1")
+	require.Zero(t, buf.Len())
+}
+
+// sizedInfo is a file info with the given size.
+type sizedInfo struct {
+	fs.FileInfo
+	size int64
+}
+
+func (i sizedInfo) Size() int64 { return i.size }
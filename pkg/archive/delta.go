@@ -0,0 +1,184 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/destination"
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// Manifest maps the destination of each file of an archive to the SHA256
+// checksum of its content, hex encoded.
+// The content of symlinks is their target.
+type Manifest map[string]string
+
+// ReadManifest reads the manifest of an existing archive in the given format,
+// which can be used as the base of a [Delta].
+// Directories are not part of manifests.
+func ReadManifest(r io.Reader, format string) (Manifest, error) {
+	sums, err := entrySums(r, format)
+	if err != nil {
+		return nil, err
+	}
+	m := make(Manifest, len(sums))
+	for name, s := range sums {
+		if strings.HasSuffix(name, "/") {
+			continue
+		}
+		m[name] = s.sum
+	}
+	return m, nil
+}
+
+// Delta is an archive which only holds the files which changed since a base
+// archive, described by its manifest: files with the same destination and
+// content as in the base are skipped.
+//
+// Explicit directories are always added.
+// Files are compared by their name in the archive, i.e. after the prefix and
+// name transformation of its options are applied.
+//
+// With [WithStats], its stats are reported by its Archive, e.g.
+// d.Archive.(StatsReporter).Stats().
+type Delta struct {
+	Archive
+	base      Manifest
+	manifest  Manifest
+	unchanged []string
+	name      func(dst string) string
+}
+
+func (d *Delta) unwrap() Archive { return d.Archive }
+
+// NewDelta creates a delta archive in the given format, against the given
+// base manifest.
+func NewDelta(w io.Writer, format string, base Manifest, opts ...Option) (*Delta, error) {
+	a, err := New(w, format, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Delta{
+		Archive:  a,
+		base:     base,
+		manifest: Manifest{},
+		name:     o.entryName,
+	}, nil
+}
+
+// Add adds the given file to the archive, unless it is unchanged.
+func (d *Delta) Add(f config.File) error {
+	if f.Source == "" {
+		return d.Archive.Add(f)
+	}
+	info, err := os.Lstat(f.Source)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Source, err)
+	}
+	var sum entrySum
+	switch {
+	case info.Mode().IsRegular():
+		file, err := os.Open(f.Source) // #nosec
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Source, err)
+		}
+		sum, err = sumOf(file)
+		_ = file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Source, err)
+		}
+	case info.Mode()&fs.ModeSymlink != 0:
+		link, err := os.Readlink(f.Source) // #nosec
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Source, err)
+		}
+		sum, _ = sumOf(strings.NewReader(link))
+	default:
+		return d.Archive.Add(f)
+	}
+	name, unchanged, err := d.check(f.Destination, sum.sum)
+	if err != nil || unchanged {
+		return err
+	}
+	if err := d.Archive.Add(f); err != nil {
+		return err
+	}
+	d.manifest[name] = sum.sum
+	return nil
+}
+
+// AddWithReader adds the given regular file, reading its content from r,
+// unless it is unchanged.
+// Its content is read twice, so r needs to implement [io.Seeker].
+func (d *Delta) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	var sum entrySum
+	if err := reread(r, func(r io.Reader) (err error) {
+		sum, err = sumOf(r)
+		return err
+	}); err != nil {
+		return fmt.Errorf("%s: %w", f.Destination, err)
+	}
+	name, unchanged, err := d.check(f.Destination, sum.sum)
+	if err != nil || unchanged {
+		return err
+	}
+	if err := addReader(d.Archive, f, info, r); err != nil {
+		return err
+	}
+	d.manifest[name] = sum.sum
+	return nil
+}
+
+// check returns the name in the archive of the given destination, and
+// whether it is unchanged since the base, recording it if so.
+func (d *Delta) check(dst, sum string) (string, bool, error) {
+	if err := destination.Validate(dst); err != nil {
+		return "", false, err
+	}
+	name := d.name(dst)
+	if _, ok := d.manifest[name]; ok {
+		return "", false, &fs.PathError{Err: fs.ErrExist, Path: name, Op: "add"}
+	}
+	if base, ok := d.base[name]; !ok || base != sum {
+		return name, false, nil
+	}
+	d.manifest[name] = sum
+	d.unchanged = append(d.unchanged, name)
+	return name, true, nil
+}
+
+// Unchanged returns the names of the files which were skipped, as they are
+// the same as in the base, sorted.
+func (d *Delta) Unchanged() []string {
+	unchanged := slices.Clone(d.unchanged)
+	slices.Sort(unchanged)
+	return unchanged
+}
+
+// Removed returns the names of the files of the base which were not added,
+// sorted.
+func (d *Delta) Removed() []string {
+	var removed []string
+	for name := range d.base {
+		if _, ok := d.manifest[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	slices.Sort(removed)
+	return removed
+}
+
+// Manifest returns the manifest of all the files added so far, including the
+// unchanged ones, which can be used as the base of the next delta.
+func (d *Delta) Manifest() Manifest {
+	return maps.Clone(d.manifest)
+}
@@ -0,0 +1,110 @@
+package archive
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goreleaser/goreleaser/v2/internal/testlib"
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelta(t *testing.T) {
+	for _, format := range []string{"tar.gz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			tmp := t.TempDir()
+			write := func(name, content string) string {
+				t.Helper()
+				src := filepath.Join(tmp, name)
+				require.NoError(t, os.WriteFile(src, []byte(content), 0o644))
+				return src
+			}
+
+			var base bytes.Buffer
+			archive, err := New(&base, format)
+			require.NoError(t, err)
+			require.NoError(t, archive.Add(config.File{Source: write("same.txt", "same"), Destination: "same.txt"}))
+			require.NoError(t, archive.Add(config.File{Source: write("old.txt", "old"), Destination: "changed.txt"}))
+			require.NoError(t, archive.Add(config.File{Source: write("gone.txt", "gone"), Destination: "gone.txt"}))
+			require.NoError(t, archive.Add(config.File{
+				Destination: "dir",
+				Info:        config.FileInfo{Mode: fs.ModeDir | 0o755},
+			}))
+			require.NoError(t, AddFS(archive, fstest.MapFS{
+				"same.txt":    {Data: []byte("same")},
+				"changed.txt": {Data: []byte("old")},
+			}, "fs"))
+			require.NoError(t, archive.Close())
+
+			manifest, err := ReadManifest(bytes.NewReader(base.Bytes()), format)
+			require.NoError(t, err)
+			require.Len(t, manifest, 5)
+
+			path := filepath.Join(tmp, "delta."+format)
+			f, err := os.Create(path)
+			require.NoError(t, err)
+			delta, err := NewDelta(f, format, manifest)
+			require.NoError(t, err)
+			require.NoError(t, delta.Add(config.File{Source: filepath.Join(tmp, "same.txt"), Destination: "same.txt"}))
+			require.NoError(t, delta.Add(config.File{Source: write("new.txt", "new"), Destination: "changed.txt"}))
+			require.NoError(t, delta.Add(config.File{Source: filepath.Join(tmp, "new.txt"), Destination: "added.txt"}))
+			require.NoError(t, delta.Add(config.File{
+				Destination: "dir",
+				Info:        config.FileInfo{Mode: fs.ModeDir | 0o755},
+			}))
+			require.NoError(t, AddFS(delta, fstest.MapFS{
+				"same.txt":    {Data: []byte("same")},
+				"changed.txt": {Data: []byte("new")},
+			}, "fs"))
+			require.ErrorIs(t, delta.Add(config.File{Source: filepath.Join(tmp, "same.txt"), Destination: "same.txt"}), fs.ErrExist)
+			require.NoError(t, delta.Close())
+			require.NoError(t, f.Close())
+
+			require.ElementsMatch(t, []string{
+				"changed.txt",
+				"added.txt",
+				"dir/",
+				"fs/changed.txt",
+			}, testlib.LsArchive(t, path, format))
+			require.Equal(t, []string{"fs/same.txt", "same.txt"}, delta.Unchanged())
+			require.Equal(t, []string{"gone.txt"}, delta.Removed())
+			require.Len(t, delta.Manifest(), 5)
+			require.Equal(t, manifest["same.txt"], delta.Manifest()["same.txt"])
+			require.NotEqual(t, manifest["changed.txt"], delta.Manifest()["changed.txt"])
+		})
+	}
+}
+
+func TestDeltaPrefixAndTransform(t *testing.T) {
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "same.txt")
+	require.NoError(t, os.WriteFile(src, []byte("same"), 0o644))
+	opts := []Option{WithPrefix("App_1.0"), WithNameTransform(strings.ToLower)}
+
+	var base bytes.Buffer
+	archive, err := New(&base, "tar", opts...)
+	require.NoError(t, err)
+	require.NoError(t, archive.Add(config.File{Source: src, Destination: "Same.txt"}))
+	require.NoError(t, AddFS(archive, fstest.MapFS{"Same.txt": {Data: []byte("same")}}, "FS"))
+	require.NoError(t, archive.Close())
+
+	manifest, err := ReadManifest(bytes.NewReader(base.Bytes()), "tar")
+	require.NoError(t, err)
+	require.Len(t, manifest, 2)
+
+	var buf bytes.Buffer
+	delta, err := NewDelta(&buf, "tar", manifest, opts...)
+	require.NoError(t, err)
+	require.NoError(t, delta.Add(config.File{Source: src, Destination: "Same.txt"}))
+	require.NoError(t, AddFS(delta, fstest.MapFS{"Same.txt": {Data: []byte("same")}}, "FS"))
+	require.NoError(t, delta.Close())
+
+	require.Equal(t, []string{"app_1.0/fs/same.txt", "app_1.0/same.txt"}, delta.Unchanged())
+	require.Empty(t, delta.Removed())
+	require.Equal(t, manifest, delta.Manifest())
+}
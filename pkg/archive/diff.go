@@ -0,0 +1,116 @@
+package archive
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"slices"
+)
+
+// Change is the kind of change of an entry between two archives.
+type Change string
+
+// Changes reported by [Diff].
+const (
+	Added   Change = "added"
+	Removed Change = "removed"
+	Changed Change = "changed"
+)
+
+// EntryDiff is an entry which differs between two archives.
+// The fields of the side in which the entry is missing are left empty.
+type EntryDiff struct {
+	Name      string
+	Change    Change
+	OldSize   int64
+	NewSize   int64
+	OldSHA256 string
+	NewSHA256 string
+}
+
+// Diff compares the entries of two archives in the given format, returning
+// the ones which were added, removed, or changed, by size or content, sorted by
+// name.
+//
+// The content of symlinks is their target.
+// Zip archives are read fully into memory, as their central directory is at
+// the end of the file.
+func Diff(a, b io.Reader, format string) ([]EntryDiff, error) {
+	old, err := entrySums(a, format)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := entrySums(b, format)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []EntryDiff
+	for name, o := range old {
+		c, ok := cur[name]
+		switch {
+		case !ok:
+			diffs = append(diffs, EntryDiff{
+				Name:      name,
+				Change:    Removed,
+				OldSize:   o.size,
+				OldSHA256: o.sum,
+			})
+		case o != c:
+			diffs = append(diffs, EntryDiff{
+				Name:      name,
+				Change:    Changed,
+				OldSize:   o.size,
+				NewSize:   c.size,
+				OldSHA256: o.sum,
+				NewSHA256: c.sum,
+			})
+		}
+	}
+	for name, c := range cur {
+		if _, ok := old[name]; !ok {
+			diffs = append(diffs, EntryDiff{
+				Name:      name,
+				Change:    Added,
+				NewSize:   c.size,
+				NewSHA256: c.sum,
+			})
+		}
+	}
+	slices.SortFunc(diffs, func(a, b EntryDiff) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return diffs, nil
+}
+
+type entrySum struct {
+	size int64
+	sum  string
+}
+
+// entrySums returns the size and SHA256 checksum of every entry of the given
+// archive, by name.
+func entrySums(r io.Reader, format string) (map[string]entrySum, error) {
+	sums := map[string]entrySum{}
+	if err := walkEntries(r, format, "diff", Limits{}, func(name string, content io.Reader) error {
+		sum, err := sumOf(content)
+		if err != nil {
+			return err
+		}
+		sums[name] = sum
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+func sumOf(r io.Reader) (entrySum, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return entrySum{}, err
+	}
+	return entrySum{size: n, sum: hex.EncodeToString(h.Sum(nil))}, nil
+}
@@ -0,0 +1,123 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	write := func(tb testing.TB, name, content string) string {
+		tb.Helper()
+		p := filepath.Join(dir, name)
+		require.NoError(tb, os.WriteFile(p, []byte(content), 0o644))
+		return p
+	}
+	sum := func(content string) string {
+		s := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(s[:])
+	}
+	build := func(tb testing.TB, format string, files map[string]string) []byte {
+		tb.Helper()
+		var buf bytes.Buffer
+		archive, err := New(&buf, format)
+		require.NoError(tb, err)
+		for dst, src := range files {
+			require.NoError(tb, archive.Add(config.File{Source: src, Destination: dst}))
+		}
+		require.NoError(tb, archive.Close())
+		return buf.Bytes()
+	}
+
+	same := write(t, "same.txt", "same\n")
+	v1 := write(t, "v1.txt", "version 1\n")
+	v2 := write(t, "v2.txt", "version 2!\n")
+	removed := write(t, "removed.txt", "removed\n")
+	added := write(t, "added.txt", "added\n")
+
+	for _, format := range []string{"tar", "tar.gz", "tar.xz", "tar.zst", "zip", "ar", "cpio"} {
+		t.Run(format, func(t *testing.T) {
+			old := build(t, format, map[string]string{
+				"same.txt":    same,
+				"version.txt": v1,
+				"removed.txt": removed,
+			})
+			cur := build(t, format, map[string]string{
+				"same.txt":    same,
+				"version.txt": v2,
+				"added.txt":   added,
+			})
+
+			diffs, err := Diff(bytes.NewReader(old), bytes.NewReader(cur), format)
+			require.NoError(t, err)
+			require.Equal(t, []EntryDiff{
+				{
+					Name:      "added.txt",
+					Change:    Added,
+					NewSize:   6,
+					NewSHA256: sum("added\n"),
+				},
+				{
+					Name:      "removed.txt",
+					Change:    Removed,
+					OldSize:   8,
+					OldSHA256: sum("removed\n"),
+				},
+				{
+					Name:      "version.txt",
+					Change:    Changed,
+					OldSize:   10,
+					NewSize:   11,
+					OldSHA256: sum("version 1\n"),
+					NewSHA256: sum("version 2!\n"),
+				},
+			}, diffs)
+
+			diffs, err = Diff(bytes.NewReader(old), bytes.NewReader(old), format)
+			require.NoError(t, err)
+			require.Empty(t, diffs)
+		})
+	}
+
+	t.Run("gz", func(t *testing.T) {
+		diffs, err := Diff(
+			bytes.NewReader(build(t, "gz", map[string]string{"version.txt": v1})),
+			bytes.NewReader(build(t, "gz", map[string]string{"version.txt": v2})),
+			"gz",
+		)
+		require.NoError(t, err)
+		require.Len(t, diffs, 1)
+		require.Equal(t, Changed, diffs[0].Change)
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		_, err := Diff(bytes.NewReader([]byte("nope")), bytes.NewReader(nil), "tar.gz")
+		require.Error(t, err)
+	})
+
+	t.Run("registered", func(t *testing.T) {
+		Register("fake", func(w io.Writer) Archive {
+			return &fakeArchive{w: w}
+		})
+		t.Cleanup(func() {
+			registryMu.Lock()
+			defer registryMu.Unlock()
+			delete(registry, "fake")
+		})
+		_, err := Diff(bytes.NewReader(nil), bytes.NewReader(nil), "fake")
+		require.EqualError(t, err, "diff not supported for archive format: fake")
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		_, err := Diff(bytes.NewReader(nil), bytes.NewReader(nil), "7z")
+		require.EqualError(t, err, "invalid archive format: 7z")
+	})
+}
@@ -0,0 +1,170 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// ErrDuplicateContent happens when a file with the same content as a
+// previously added one is added, and [Duplicates.Strict] is set.
+var ErrDuplicateContent = errors.New("duplicate content")
+
+// Duplicates reports files added with the same content as previously added
+// ones, which waste space, as archives can't store the content only once.
+//
+// See [WithDuplicates].
+type Duplicates struct {
+	// Strict makes Add fail with [ErrDuplicateContent] when a duplicate is
+	// found, instead of only reporting it.
+	Strict bool
+
+	// Count is the number of duplicates found.
+	Count int
+
+	// WastedBytes is the sum of the sizes of all duplicates.
+	WastedBytes int64
+
+	// Files maps the destination of each duplicate to the destination of the
+	// file with the same content which was added first.
+	Files map[string]string
+
+	mu   sync.Mutex
+	seen map[[sha256.Size]byte]string
+}
+
+// WithDuplicates makes the archive look for files with the same content,
+// reporting them in the given [Duplicates].
+//
+// Every added file is read twice, once to compute its checksum, and once to
+// actually add it.
+func WithDuplicates(d *Duplicates) Option {
+	return func(o *options) {
+		o.duplicates = d
+	}
+}
+
+// content is the checksum and size of a file added at the given destination.
+type content struct {
+	dst  string
+	sum  [sha256.Size]byte
+	size int64
+}
+
+func contentOf(dst string, r io.Reader) (content, error) {
+	h := sha256.New()
+	size, err := io.Copy(h, r)
+	if err != nil {
+		return content{}, err
+	}
+	c := content{dst: dst, size: size}
+	h.Sum(c.sum[:0])
+	return c, nil
+}
+
+// check fails with [ErrDuplicateContent] in strict mode, if the given content
+// was seen before under a different destination.
+func (d *Duplicates) check(c content) error {
+	if !d.Strict {
+		return nil
+	}
+	d.mu.Lock()
+	first, ok := d.seen[c.sum]
+	d.mu.Unlock()
+	if ok && first != c.dst {
+		return fmt.Errorf("%s: %w with %s", c.dst, ErrDuplicateContent, first)
+	}
+	return nil
+}
+
+// record marks the given content as seen, once it was actually added,
+// reporting it if seen before under a different destination.
+func (d *Duplicates) record(c content) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	first, ok := d.seen[c.sum]
+	if first == c.dst {
+		return
+	}
+	if !ok {
+		if d.seen == nil {
+			d.seen = map[[sha256.Size]byte]string{}
+		}
+		d.seen[c.sum] = c.dst
+		return
+	}
+	if d.Files == nil {
+		d.Files = map[string]string{}
+	}
+	d.Files[c.dst] = first
+	d.Count++
+	d.WastedBytes += c.size
+}
+
+type duplicatesArchive struct {
+	Archive
+	d *Duplicates
+}
+
+func (a duplicatesArchive) unwrap() Archive { return a.Archive }
+
+func (a duplicatesArchive) Add(f config.File) error {
+	if f.Source == "" {
+		return a.Archive.Add(f)
+	}
+	// symlinks are added as links, so their targets are not duplicated.
+	info, err := os.Lstat(f.Source)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() || info.Size() == 0 {
+		return a.Archive.Add(f)
+	}
+	file, err := os.Open(f.Source) // #nosec
+	if err != nil {
+		return err
+	}
+	c, err := contentOf(f.Destination, file)
+	_ = file.Close()
+	if err != nil {
+		return err
+	}
+	return a.add(c, func() error {
+		return a.Archive.Add(f)
+	})
+}
+
+func (a duplicatesArchive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	if info.Size() == 0 {
+		return addReader(a.Archive, f, info, r)
+	}
+	var c content
+	if err := reread(r, func(r io.Reader) (err error) {
+		c, err = contentOf(f.Destination, r)
+		return err
+	}); err != nil {
+		return fmt.Errorf("%s: %w", f.Destination, err)
+	}
+	return a.add(c, func() error {
+		return addReader(a.Archive, f, info, r)
+	})
+}
+
+// add adds a file with the given content, unless it is a duplicate in strict
+// mode, recording it once added.
+func (a duplicatesArchive) add(c content, add func() error) error {
+	if err := a.d.check(c); err != nil {
+		return err
+	}
+	if err := add(); err != nil {
+		return err
+	}
+	a.d.record(c)
+	return nil
+}
@@ -0,0 +1,98 @@
+package archive
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	big := filepath.Join(dir, "big.bin")
+	require.NoError(t, os.WriteFile(big, make([]byte, 1024), 0o644))
+	empty := filepath.Join(dir, "empty.txt")
+	require.NoError(t, os.WriteFile(empty, nil, 0o644))
+
+	files := []config.File{
+		{Source: big, Destination: "bin/app"},
+		{Source: big, Destination: "bin/app-copy"},
+		{Source: "testdata/foo.txt", Destination: "foo.txt"},
+		{Source: big, Destination: "lib/app"},
+		{Source: empty, Destination: "empty1.txt"},
+		{Source: empty, Destination: "empty2.txt"},
+		{Source: "testdata/sub1", Destination: "sub1"},
+	}
+
+	for _, format := range []string{"tar.gz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			var d Duplicates
+			archive, err := New(io.Discard, format, WithDuplicates(&d))
+			require.NoError(t, err)
+			for _, f := range files {
+				require.NoError(t, archive.Add(f))
+			}
+			require.NoError(t, AddFS(archive, fstest.MapFS{
+				"foo.txt": {Data: []byte("foo\n"), Mode: 0o644},
+				"bar.txt": {Data: []byte("bar\n"), Mode: 0o644},
+			}, "fs"))
+			require.NoError(t, archive.Close())
+
+			require.Equal(t, 3, d.Count)
+			require.Equal(t, int64(1024*2+4), d.WastedBytes)
+			require.Equal(t, map[string]string{
+				"bin/app-copy": "bin/app",
+				"lib/app":      "bin/app",
+				"fs/foo.txt":   "foo.txt",
+			}, d.Files)
+		})
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		d := Duplicates{Strict: true}
+		archive, err := New(io.Discard, "tar", WithDuplicates(&d))
+		require.NoError(t, err)
+		require.NoError(t, archive.Add(files[0]))
+		err = archive.Add(files[1])
+		require.ErrorIs(t, err, ErrDuplicateContent)
+		require.EqualError(t, err, "bin/app-copy: duplicate content with bin/app")
+		require.NoError(t, archive.Add(files[2]))
+		require.NoError(t, archive.Close())
+		require.Zero(t, d.Count)
+	})
+
+	t.Run("symlink", func(t *testing.T) {
+		d := Duplicates{Strict: true}
+		archive, err := New(io.Discard, "tar", WithDuplicates(&d))
+		require.NoError(t, err)
+		require.NoError(t, archive.Add(config.File{Source: "testdata/regular.txt", Destination: "regular.txt"}))
+		require.NoError(t, archive.Add(config.File{Source: "testdata/link.txt", Destination: "link.txt"}))
+		require.NoError(t, archive.Close())
+		require.Zero(t, d.Count)
+	})
+
+	t.Run("failed add", func(t *testing.T) {
+		d := Duplicates{Strict: true}
+		archive, err := New(io.Discard, "tar", WithDuplicates(&d))
+		require.NoError(t, err)
+		require.Error(t, archive.Add(config.File{Source: big, Destination: "../bin/app"}))
+		require.NoError(t, archive.Add(files[0]))
+		require.NoError(t, archive.Close())
+		require.Zero(t, d.Count)
+	})
+
+	t.Run("strict fs", func(t *testing.T) {
+		d := Duplicates{Strict: true}
+		archive, err := New(io.Discard, "tar", WithDuplicates(&d))
+		require.NoError(t, err)
+		require.ErrorIs(t, AddFS(archive, fstest.MapFS{
+			"a.txt": {Data: []byte("foo\n"), Mode: 0o644},
+			"b.txt": {Data: []byte("foo\n"), Mode: 0o644},
+		}, "fs"), ErrDuplicateContent)
+		require.NoError(t, archive.Close())
+	})
+}
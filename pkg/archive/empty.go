@@ -0,0 +1,74 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// ErrEmptyFile happens when adding an empty file to an archive created with
+// [WithRejectEmptyFiles].
+var ErrEmptyFile = errors.New("empty file")
+
+// WithRejectEmptyFiles makes adding files fail with [ErrEmptyFile] when
+// adding an empty regular file, which is usually the broken output of a
+// failed build, unless its destination, or its base name, matches one of the
+// given patterns, e.g. ".keep".
+// Explicit directories and symlinks are not checked.
+func WithRejectEmptyFiles(allowed ...string) Option {
+	return func(o *options) {
+		o.rejectEmpty = true
+		o.allowedEmpty = allowed
+	}
+}
+
+type rejectEmptyArchive struct {
+	Archive
+	allowed []string
+}
+
+func (a rejectEmptyArchive) unwrap() Archive { return a.Archive }
+
+func (a rejectEmptyArchive) Add(f config.File) error {
+	if f.Source != "" {
+		info, err := os.Lstat(f.Source)
+		if err != nil {
+			return err
+		}
+		if err := a.check(f.Destination, info); err != nil {
+			return err
+		}
+	}
+	return a.Archive.Add(f)
+}
+
+func (a rejectEmptyArchive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	if err := a.check(f.Destination, info); err != nil {
+		return err
+	}
+	return addReader(a.Archive, f, info, r)
+}
+
+// check fails if the given file is empty, and not allowed to be.
+func (a rejectEmptyArchive) check(dst string, info fs.FileInfo) error {
+	if !info.Mode().IsRegular() || info.Size() > 0 {
+		return nil
+	}
+	for _, pattern := range a.allowed {
+		for _, name := range []string{dst, path.Base(dst)} {
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return fmt.Errorf("invalid allowed empty file pattern %q: %w", pattern, err)
+			}
+			if ok {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%s: %w", dst, ErrEmptyFile)
+}
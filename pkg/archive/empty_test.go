@@ -0,0 +1,52 @@
+package archive
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRejectEmptyFiles(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty")
+	require.NoError(t, os.WriteFile(empty, nil, 0o755))
+
+	t.Run("off", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar.gz")
+		require.NoError(t, err)
+		require.NoError(t, archive.Add(config.File{Source: empty, Destination: "bin/app"}))
+		require.NoError(t, archive.Close())
+	})
+
+	for _, format := range []string{"tar.gz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			archive, err := New(io.Discard, format, WithRejectEmptyFiles(".keep", "docs/*.md"))
+			require.NoError(t, err)
+			require.NoError(t, archive.Add(config.File{Source: "testdata/foo.txt", Destination: "foo.txt"}))
+			require.EqualError(t, archive.Add(config.File{Source: empty, Destination: "bin/app"}), "bin/app: empty file")
+			require.NoError(t, archive.Add(config.File{Source: empty, Destination: "logs/.keep"}))
+			require.NoError(t, archive.Add(config.File{Source: empty, Destination: "docs/empty.md"}))
+			require.ErrorIs(t, archive.Add(config.File{Source: empty, Destination: "empty.md"}), ErrEmptyFile)
+			require.NoError(t, archive.Add(config.File{Source: "testdata/sub1", Destination: "sub1"}))
+
+			require.NoError(t, AddFS(archive, fstest.MapFS{
+				"bar.txt": {Data: []byte("bar\n")},
+				".keep":   {},
+			}, "fs"))
+			require.ErrorIs(t, AddFS(archive, fstest.MapFS{
+				"bin/app": {},
+			}, "other"), ErrEmptyFile)
+			require.NoError(t, archive.Close())
+		})
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar", WithRejectEmptyFiles("["))
+		require.NoError(t, err)
+		require.ErrorContains(t, archive.Add(config.File{Source: empty, Destination: "app"}), `invalid allowed empty file pattern "["`)
+	})
+}
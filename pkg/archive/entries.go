@@ -0,0 +1,267 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// walkEntries calls fn with the name and content of every entry of the given
+// archive, in order, failing with [ErrLimitExceeded] as soon as the archive
+// goes over the given limits.
+//
+// The content of symlinks is their target, and the names of directories end
+// with a slash.
+// Zip archives are read fully into memory, as their central directory is at
+// the end of the file.
+//
+// The given operation is only used to tell it apart in the error of
+// registered formats, which can't be read.
+func walkEntries(r io.Reader, format, op string, limits Limits, fn func(name string, content io.Reader) error) error {
+	entries := 0
+	visit := func(name string, content io.Reader) error {
+		entries++
+		if limits.MaxEntries > 0 && entries > limits.MaxEntries {
+			return fmt.Errorf("%w: more than %d entries", ErrLimitExceeded, limits.MaxEntries)
+		}
+		if err := fn(name, content); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	}
+
+	switch format {
+	case "tar":
+		return walkTar(newLimitReader(r, limits.MaxBytes), visit)
+	case "tar.gz", "tgz":
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		return walkCompressedTar(gr, limits, visit)
+	case "tar.xz", "txz":
+		xzr, err := xz.NewReader(r)
+		if err != nil {
+			return err
+		}
+		return walkCompressedTar(xzr, limits, visit)
+	case "tar.zst", "tzst":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return walkCompressedTar(zr, limits, visit)
+	case "gz":
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		return visit(gr.Name, newLimitReader(gr, limits.MaxBytes))
+	case "zip":
+		return walkZip(r, limits, visit)
+	case "ar":
+		return walkAr(newLimitReader(r, limits.MaxBytes), visit)
+	case "cpio":
+		return walkCpio(newLimitReader(r, limits.MaxBytes), visit)
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if _, ok := registry[format]; ok {
+		return fmt.Errorf("%s not supported for archive format: %s", op, format)
+	}
+	return fmt.Errorf("invalid archive format: %s", format)
+}
+
+func walkTar(r io.Reader, fn func(name string, content io.Reader) error) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		content := io.Reader(tr)
+		if header.Typeflag == tar.TypeSymlink || header.Typeflag == tar.TypeLink {
+			content = strings.NewReader(header.Linkname)
+		}
+		if err := fn(header.Name, content); err != nil {
+			return err
+		}
+	}
+}
+
+// walkCompressedTar walks the tar inside the given decompressed stream, and
+// then reads whatever is left of it, so the checksums at its end are verified
+// too.
+func walkCompressedTar(r io.Reader, limits Limits, fn func(name string, content io.Reader) error) error {
+	lr := newLimitReader(r, limits.MaxBytes)
+	if err := walkTar(lr, fn); err != nil {
+		return err
+	}
+	_, err := io.Copy(io.Discard, lr)
+	return err
+}
+
+func walkZip(r io.Reader, limits Limits, fn func(name string, content io.Reader) error) error {
+	bts, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(bts), int64(len(bts)))
+	if err != nil {
+		return err
+	}
+	if limits.MaxEntries > 0 && len(zr.File) > limits.MaxEntries {
+		return fmt.Errorf("%w: more than %d entries", ErrLimitExceeded, limits.MaxEntries)
+	}
+	size := &limitReader{limit: limits.MaxBytes}
+	for _, f := range zr.File {
+		if err := walkZipFile(f, size, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkZipFile(f *zip.File, lr *limitReader, fn func(name string, content io.Reader) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	lr.r = rc
+	return fn(f.Name, lr)
+}
+
+// walkAr walks the members of an ar archive, in the classic format.
+func walkAr(r io.Reader, fn func(name string, content io.Reader) error) error {
+	magic := make([]byte, 8)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return err
+	}
+	if string(magic) != "!<arch>\n" {
+		return errors.New("ar: invalid magic")
+	}
+	header := make([]byte, 60)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if string(header[58:]) != "`\n" {
+			return errors.New("ar: invalid member header")
+		}
+		// GNU ar terminates names with a slash.
+		name := strings.TrimSuffix(strings.TrimRight(string(header[:16]), " "), "/")
+		size, err := strconv.ParseInt(strings.TrimRight(string(header[48:58]), " "), 10, 64)
+		if err != nil || size < 0 {
+			return fmt.Errorf("%s: ar: invalid member size", name)
+		}
+		if err := walkSized(r, name, size, fn); err != nil {
+			return err
+		}
+		// members are aligned to 2 bytes.
+		if size%2 != 0 {
+			if _, err := io.ReadFull(r, header[:1]); err != nil {
+				return fmt.Errorf("%s: %w", name, noEOF(err))
+			}
+		}
+	}
+}
+
+// cpioMaxName is the maximum size of the names of cpio entries, including
+// their terminating NUL, as PATH_MAX on Linux.
+const cpioMaxName = 4096
+
+// walkCpio walks the entries of a cpio archive, in the newc format.
+func walkCpio(r io.Reader, fn func(name string, content io.Reader) error) error {
+	header := make([]byte, 110)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return noEOF(err)
+		}
+		if magic := string(header[:6]); magic != "070701" && magic != "070702" {
+			return errors.New("cpio: invalid magic")
+		}
+		// 13 fields of 8 hexadecimal digits follow the magic.
+		var fields [13]int64
+		for i := range fields {
+			v, err := strconv.ParseUint(string(header[6+i*8:14+i*8]), 16, 32)
+			if err != nil {
+				return errors.New("cpio: invalid header")
+			}
+			fields[i] = int64(v)
+		}
+		mode, size, nameSize := fields[1], fields[6], fields[11]
+		if nameSize == 0 {
+			return errors.New("cpio: invalid header")
+		}
+		// the name size is checked before its buffer is allocated, as it can
+		// be as large as 4 GiB in crafted archives.
+		if nameSize > cpioMaxName {
+			return fmt.Errorf("cpio: name too long: %d bytes", nameSize)
+		}
+		// the name is padded along with the header to 4 bytes.
+		raw := make([]byte, nameSize+pad4(110+nameSize))
+		if _, err := io.ReadFull(r, raw); err != nil {
+			return noEOF(err)
+		}
+		name := string(raw[:nameSize-1])
+		if name == "TRAILER!!!" {
+			return nil
+		}
+		if mode&0o170000 == 0o040000 {
+			name += "/"
+		}
+		if err := walkSized(r, name, size, fn); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(r, raw[:pad4(size)]); err != nil {
+			return fmt.Errorf("%s: %w", name, noEOF(err))
+		}
+	}
+}
+
+// walkSized calls fn with the next size bytes of r, which are then skipped
+// if not fully read.
+func walkSized(r io.Reader, name string, size int64, fn func(name string, content io.Reader) error) error {
+	content := &io.LimitedReader{R: r, N: size}
+	if err := fn(name, content); err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.Discard, content); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if content.N > 0 {
+		return fmt.Errorf("%s: %w", name, io.ErrUnexpectedEOF)
+	}
+	return nil
+}
+
+// pad4 returns the number of bytes needed to align n to 4 bytes.
+func pad4(n int64) int64 {
+	return (4 - n%4) % 4
+}
+
+// noEOF converts [io.EOF] to [io.ErrUnexpectedEOF], for archives which end
+// before their trailer.
+func noEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
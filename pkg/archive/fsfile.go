@@ -0,0 +1,141 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"time"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/destination"
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// ReaderAdder is implemented by archives which can add a regular file whose
+// content is read from a reader, instead of from its source in the disk, as
+// done by [AddFS] and [AddFSFile].
+//
+// It is implemented by all the built-in formats.
+type ReaderAdder interface {
+	// AddWithReader adds a regular file at the destination of the given file,
+	// reading its content from r, with the size, mode and modification time
+	// of the given info, unless set in the file info.
+	// The source of the file is ignored.
+	AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error
+}
+
+// AddFS adds all the regular files of the given file system, e.g. an
+// [embed.FS], to the archive, with their paths prefixed by the given prefix,
+// and the mode and modification time of their file info.
+//
+// Archives which don't implement [ReaderAdder] get each file copied into a
+// temporary file first, which is removed once the archive is closed.
+func AddFS(a Archive, fsys fs.FS, prefix string) error {
+	if err := destination.Validate(prefix); err != nil {
+		return err
+	}
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		file, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return AddFSFile(a, path.Join(prefix, name), file)
+	})
+}
+
+// AddFSFile adds the given open file, e.g. from an [embed.FS], to the archive
+// at the given destination, streaming its content.
+// Its mode, size and modification time are taken from its Stat.
+//
+// Only regular files are supported, and the file is not closed.
+// If the archive needs to read it more than once, e.g. when using
+// [WithDuplicates], the file needs to implement [io.Seeker].
+func AddFSFile(a Archive, dst string, file fs.File) error {
+	if err := destination.Validate(dst); err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("%s: %w", dst, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s: not a regular file: %s", dst, info.Mode().Type())
+	}
+	return addReader(a, config.File{Destination: dst}, info, file)
+}
+
+// addReader adds a regular file with the content read from r to the archive,
+// which, if it doesn't implement [ReaderAdder], gets it through a temporary
+// file, removed once it is closed.
+func addReader(a Archive, f config.File, info fs.FileInfo, r io.Reader) error {
+	if ra, ok := a.(ReaderAdder); ok {
+		return ra.AddWithReader(f, info, r)
+	}
+	tmp, err := os.CreateTemp("", "goreleaser-archive-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, r)
+	if err := errors.Join(err, tmp.Close()); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("%s: %w", f.Destination, err)
+	}
+	f.Source = tmp.Name()
+	if f.Info.Mode == 0 {
+		f.Info.Mode = info.Mode()
+	}
+	if f.Info.ParsedMTime.IsZero() {
+		f.Info.ParsedMTime = info.ModTime()
+	}
+	if err := a.Add(f); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	afterClose(a, func() { _ = os.Remove(tmp.Name()) })
+	return nil
+}
+
+var errNotSeekable = errors.New("file can only be read once")
+
+// reread calls fn with r, and then rewinds it, so it can be read again,
+// failing if it is not an [io.Seeker].
+func reread(r io.Reader, fn func(r io.Reader) error) error {
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return errNotSeekable
+	}
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if err := fn(r); err != nil {
+		return err
+	}
+	_, err = seeker.Seek(offset, io.SeekStart)
+	return err
+}
+
+// readerInfo is the info of a file whose content is read from a reader, e.g.
+// a response body.
+type readerInfo struct {
+	name  string
+	size  int64
+	mode  fs.FileMode
+	mtime time.Time
+}
+
+func (i readerInfo) Name() string       { return i.name }
+func (i readerInfo) Size() int64        { return i.size }
+func (i readerInfo) Mode() fs.FileMode  { return i.mode }
+func (i readerInfo) ModTime() time.Time { return i.mtime }
+func (i readerInfo) IsDir() bool        { return false }
+func (i readerInfo) Sys() any           { return nil }
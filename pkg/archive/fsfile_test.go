@@ -0,0 +1,93 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddFSFile(t *testing.T) {
+	mtime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"app":     {Data: []byte("#!/bin/sh\necho hi\n"), Mode: 0o755, ModTime: mtime},
+		"dir/sub": {Mode: fs.ModeDir | 0o755},
+	}
+
+	open := func(tb testing.TB, name string) fs.File {
+		tb.Helper()
+		f, err := fsys.Open(name)
+		require.NoError(tb, err)
+		tb.Cleanup(func() { f.Close() })
+		return f
+	}
+
+	t.Run("tar", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive, err := New(&buf, "tar")
+		require.NoError(t, err)
+		require.NoError(t, AddFSFile(archive, "bin/app", open(t, "app")))
+		require.NoError(t, archive.Close())
+
+		r := tar.NewReader(&buf)
+		header, err := r.Next()
+		require.NoError(t, err)
+		require.Equal(t, "bin/app", header.Name)
+		require.Equal(t, int64(0o755), header.Mode)
+		require.Equal(t, int64(18), header.Size)
+		require.True(t, mtime.Equal(header.ModTime))
+		bts, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, "#!/bin/sh\necho hi\n", string(bts))
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive, err := New(&buf, "zip")
+		require.NoError(t, err)
+		require.NoError(t, AddFSFile(archive, "app", open(t, "app")))
+		require.NoError(t, archive.Close())
+
+		r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		require.Len(t, r.File, 1)
+		require.Equal(t, "app", r.File[0].Name)
+		require.Equal(t, fs.FileMode(0o755), r.File[0].Mode().Perm())
+		require.Equal(t, uint64(18), r.File[0].UncompressedSize64)
+	})
+
+	t.Run("read twice", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar", WithDuplicates(&Duplicates{}))
+		require.NoError(t, err)
+		defer archive.Close()
+		require.NoError(t, AddFSFile(archive, "app", open(t, "app")))
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar")
+		require.NoError(t, err)
+		defer archive.Close()
+		require.NoError(t, AddFSFile(archive, "app", open(t, "app")))
+		require.ErrorIs(t, AddFSFile(archive, "app", open(t, "app")), ErrDuplicateEntry)
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar")
+		require.NoError(t, err)
+		defer archive.Close()
+		require.EqualError(t, AddFSFile(archive, "sub", open(t, "dir/sub")), "sub: not a regular file: d---------")
+	})
+
+	t.Run("unsafe", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar")
+		require.NoError(t, err)
+		defer archive.Close()
+		require.Error(t, AddFSFile(archive, "../app", open(t, "app")))
+	})
+}
@@ -3,17 +3,38 @@
 package gzip
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
+	"hash"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/closed"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/destination"
 	"github.com/goreleaser/goreleaser/v2/pkg/config"
 	gzip "github.com/klauspost/pgzip"
 )
 
+// ChecksumID is the subfield ID of the gzip extra field used to store the
+// SHA256 checksum of the payload in archives created by [NewWithChecksum].
+const ChecksumID = "S2"
+
+// Header is the gzip header, with the original file name, comment,
+// modification time, and so on.
+type Header = gzip.Header
+
 // Archive as gz.
 type Archive struct {
-	gw *gzip.Writer
+	gw     *gzip.Writer
+	target io.Writer
+	sum    hash.Hash
+	added  *bool
+	header bool
+	closed *closed.Flag
 }
 
 // New gz archive.
@@ -21,20 +42,80 @@ func New(target io.Writer) Archive {
 	// the error will be nil since the compression level is valid
 	gw, _ := gzip.NewWriterLevel(target, gzip.BestCompression)
 	return Archive{
-		gw: gw,
+		gw:     gw,
+		added:  new(bool),
+		closed: &closed.Flag{},
+	}
+}
+
+// NewWithHeader creates a gz archive with the given header.
+//
+// The header is used as is, instead of being set from the added file, so,
+// for instance, a zero modification time is kept as zero, which is useful
+// for reproducible builds.
+func NewWithHeader(target io.Writer, header Header) Archive {
+	a := New(target)
+	if header.ModTime.IsZero() {
+		// pgzip does not handle zero times, which are stored as 0.
+		header.ModTime = time.Unix(0, 0)
 	}
+	a.gw.Header = header
+	a.header = true
+	return a
+}
+
+// NewWithChecksum creates a gz archive which also carries the SHA256 checksum
+// of its payload.
+//
+// The checksum is stored in a second, empty, gzip member, appended after the
+// payload, in the extra field subfield identified by [ChecksumID].
+// Since concatenated gzip members are decompressed as a single stream, the
+// decompressed payload is unchanged.
+func NewWithChecksum(target io.Writer) Archive {
+	a := New(target)
+	a.target = target
+	a.sum = sha256.New()
+	return a
+}
+
+// Format returns the archive format, "gz".
+func (a Archive) Format() string {
+	return "gz"
 }
 
 // Close all closeables.
 func (a Archive) Close() error {
-	return a.gw.Close()
+	if err := a.closed.Close(); err != nil {
+		return err
+	}
+	if err := a.gw.Close(); err != nil {
+		return err
+	}
+	if a.sum == nil {
+		return nil
+	}
+	sum := a.sum.Sum(nil)
+	extra := make([]byte, 4, 4+len(sum))
+	copy(extra, ChecksumID)
+	binary.LittleEndian.PutUint16(extra[2:], uint16(len(sum)))
+	gw := gzip.NewWriter(a.target)
+	gw.Extra = append(extra, sum...)
+	return gw.Close()
 }
 
 // Add file to the archive.
 func (a Archive) Add(f config.File) error {
-	if a.gw.Name != "" {
+	// entry names always use forward slashes, even when built on Windows.
+	f.Destination = filepath.ToSlash(f.Destination)
+	if err := a.closed.Check(f.Destination); err != nil {
+		return err
+	}
+	if *a.added {
 		return fmt.Errorf("gzip: failed to add %s, only one file can be archived in gz format", f.Destination)
 	}
+	if err := destination.Validate(f.Destination); err != nil {
+		return err
+	}
 	file, err := os.Open(f.Source) // #nosec
 	if err != nil {
 		return err
@@ -47,12 +128,56 @@ func (a Archive) Add(f config.File) error {
 	if info.IsDir() {
 		return nil
 	}
-	a.gw.Name = f.Destination
-	if f.Info.ParsedMTime.IsZero() {
-		a.gw.ModTime = info.ModTime()
-	} else {
-		a.gw.ModTime = f.Info.ParsedMTime
+	mtime := f.Info.ParsedMTime
+	if mtime.IsZero() {
+		mtime = info.ModTime()
+	}
+	a.setHeader(f.Destination, mtime)
+	return a.copy(file)
+}
+
+// AddWithReader adds a regular file to the archive, reading its content from
+// r, with the modification time of the given info, unless set in the file
+// info.
+func (a Archive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	// entry names always use forward slashes, even when built on Windows.
+	f.Destination = filepath.ToSlash(f.Destination)
+	if err := a.closed.Check(f.Destination); err != nil {
+		return err
+	}
+	if *a.added {
+		return fmt.Errorf("gzip: failed to add %s, only one file can be archived in gz format", f.Destination)
+	}
+	if err := destination.Validate(f.Destination); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s: not a regular file: %s", f.Destination, info.Mode().Type())
+	}
+	mtime := f.Info.ParsedMTime
+	if mtime.IsZero() {
+		mtime = info.ModTime()
+	}
+	a.setHeader(f.Destination, mtime)
+	return a.copy(r)
+}
+
+// setHeader marks the archive as having its file added, and sets the header
+// name and modification time, unless a header was given to [NewWithHeader].
+func (a Archive) setHeader(name string, mtime time.Time) {
+	*a.added = true
+	if a.header {
+		return
+	}
+	a.gw.Name = name
+	a.gw.ModTime = mtime
+}
+
+func (a Archive) copy(r io.Reader) error {
+	var w io.Writer = a.gw
+	if a.sum != nil {
+		w = io.MultiWriter(a.gw, a.sum)
 	}
-	_, err = io.Copy(a.gw, file)
+	_, err := io.Copy(w, r)
 	return err
 }
@@ -1,11 +1,17 @@
 package gzip
 
 import (
+	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/goreleaser/goreleaser/v2/pkg/config"
@@ -50,6 +56,27 @@ func TestGzFile(t *testing.T) {
 	require.Equal(t, "sub\n", string(bts))
 }
 
+func TestGzFileAddWithReader(t *testing.T) {
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	info, err := fs.Stat(fstest.MapFS{"foo.txt": {Data: []byte("foo"), ModTime: mtime}}, "foo.txt")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	archive := New(&buf)
+	require.NoError(t, archive.AddWithReader(config.File{Destination: "foo.txt"}, info, strings.NewReader("foo")))
+	require.EqualError(t, archive.AddWithReader(config.File{Destination: "bar.txt"}, info, strings.NewReader("bar")),
+		"gzip: failed to add bar.txt, only one file can be archived in gz format")
+	require.NoError(t, archive.Close())
+
+	gzf, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	require.Equal(t, "foo.txt", gzf.Name)
+	require.True(t, mtime.Equal(gzf.ModTime))
+	bts, err := io.ReadAll(gzf)
+	require.NoError(t, err)
+	require.Equal(t, "foo", string(bts))
+}
+
 func TestGzFileCustomMtime(t *testing.T) {
 	f, err := os.Create(filepath.Join(t.TempDir(), "test.gz"))
 	require.NoError(t, err)
@@ -84,3 +111,78 @@ func TestGzFileCustomMtime(t *testing.T) {
 	require.Equal(t, "sub1/sub2/subfoo.txt", gzf.Name)
 	require.Equal(t, now, gzf.ModTime)
 }
+
+func TestGzFileWithChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	archive := NewWithChecksum(&buf)
+	require.NoError(t, archive.Add(config.File{
+		Destination: "foo.txt",
+		Source:      "../testdata/foo.txt",
+	}))
+	require.NoError(t, archive.Close())
+
+	gzf, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	bts, err := io.ReadAll(gzf)
+	require.NoError(t, err)
+	require.Equal(t, "foo\n", string(bts))
+
+	r := bytes.NewReader(buf.Bytes())
+	gzf, err = gzip.NewReader(r)
+	require.NoError(t, err)
+	gzf.Multistream(false)
+	bts, err = io.ReadAll(gzf)
+	require.NoError(t, err)
+	require.Equal(t, "foo\n", string(bts))
+	require.Equal(t, "foo.txt", gzf.Name)
+
+	require.NoError(t, gzf.Reset(r))
+	gzf.Multistream(false)
+	bts, err = io.ReadAll(gzf)
+	require.NoError(t, err)
+	require.Empty(t, bts)
+
+	sum := sha256.Sum256([]byte("foo\n"))
+	require.Len(t, gzf.Extra, 4+len(sum))
+	require.Equal(t, ChecksumID, string(gzf.Extra[:2]))
+	require.Equal(t, uint16(len(sum)), binary.LittleEndian.Uint16(gzf.Extra[2:4]))
+	require.Equal(t, sum[:], gzf.Extra[4:])
+	require.ErrorIs(t, gzf.Reset(r), io.EOF)
+}
+
+func TestGzFileWithHeader(t *testing.T) {
+	mtime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, header := range map[string]Header{
+		"custom": {
+			Name:    "original.txt",
+			Comment: "built by goreleaser",
+			ModTime: mtime,
+		},
+		"zero mtime": {
+			Name: "original.txt",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			archive := NewWithHeader(&buf, header)
+			require.NoError(t, archive.Add(config.File{
+				Destination: "foo.txt",
+				Source:      "../testdata/foo.txt",
+			}))
+			require.EqualError(t, archive.Add(config.File{
+				Destination: "bar.txt",
+				Source:      "../testdata/sub1/bar.txt",
+			}), "gzip: failed to add bar.txt, only one file can be archived in gz format")
+			require.NoError(t, archive.Close())
+
+			gzf, err := gzip.NewReader(&buf)
+			require.NoError(t, err)
+			require.Equal(t, header.Name, gzf.Name)
+			require.Equal(t, header.Comment, gzf.Comment)
+			require.True(t, header.ModTime.Equal(gzf.ModTime), "expected %v, got %v", header.ModTime, gzf.ModTime)
+			bts, err := io.ReadAll(gzf)
+			require.NoError(t, err)
+			require.Equal(t, "foo\n", string(bts))
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package archive
+
+import (
+	"crypto"
+	_ "crypto/sha256" // registers the SHA256 hash functions.
+	_ "crypto/sha512" // registers the SHA512 hash functions.
+	"fmt"
+	"hash"
+
+	_ "golang.org/x/crypto/blake2b" // registers the BLAKE2b hash functions.
+	"lukechampine.com/blake3"
+)
+
+// Hasher computes the checksums of everything written to it, using multiple
+// hash algorithms at once.
+//
+// It can be passed to [New] with [WithHasher] to compute the checksums of an
+// archive while it is written.
+type Hasher struct {
+	hashes map[crypto.Hash]hash.Hash
+	blake3 hash.Hash
+}
+
+// NewHasher creates a [Hasher] using the given hash algorithms, which must be
+// linked into the binary, e.g. by importing crypto/sha256.
+// The SHA256, SHA512 and BLAKE2b algorithms are always available.
+func NewHasher(algorithms ...crypto.Hash) (*Hasher, error) {
+	h := &Hasher{
+		hashes: map[crypto.Hash]hash.Hash{},
+	}
+	for _, algorithm := range algorithms {
+		if !algorithm.Available() {
+			return nil, fmt.Errorf("hash algorithm not available: %s", algorithm)
+		}
+		h.hashes[algorithm] = algorithm.New()
+	}
+	return h, nil
+}
+
+// Write writes p to all the hashes.
+func (h *Hasher) Write(p []byte) (int, error) {
+	for _, hh := range h.hashes {
+		// hashes never return errors.
+		_, _ = hh.Write(p)
+	}
+	if h.blake3 != nil {
+		_, _ = h.blake3.Write(p)
+	}
+	return len(p), nil
+}
+
+// UseBLAKE3 makes the hasher also compute the BLAKE3-256 checksum, which is
+// much faster than SHA256 for large archives, but is not a [crypto.Hash], so
+// it is returned by [Hasher.BLAKE3Sum] instead of [Hasher.Sums].
+//
+// It must be called before anything is written to the hasher.
+func (h *Hasher) UseBLAKE3() {
+	h.blake3 = blake3.New(32, nil)
+}
+
+// BLAKE3Sum returns the BLAKE3-256 checksum of everything written so far, or
+// nil if [Hasher.UseBLAKE3] was not called.
+func (h *Hasher) BLAKE3Sum() []byte {
+	if h.blake3 == nil {
+		return nil
+	}
+	return h.blake3.Sum(nil)
+}
+
+// Sums returns the checksum of everything written so far, by algorithm.
+func (h *Hasher) Sums() map[crypto.Hash][]byte {
+	sums := make(map[crypto.Hash][]byte, len(h.hashes))
+	for algorithm, hh := range h.hashes {
+		sums[algorithm] = hh.Sum(nil)
+	}
+	return sums
+}
@@ -0,0 +1,77 @@
+package archive
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+	"lukechampine.com/blake3"
+)
+
+func TestHasher(t *testing.T) {
+	for _, format := range []string{"tar", "tar.gz", "tar.xz", "tar.zst", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			hasher, err := NewHasher(crypto.SHA256, crypto.SHA512, crypto.BLAKE2b_256)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			archive, err := New(&buf, format, WithHasher(hasher))
+			require.NoError(t, err)
+			require.NoError(t, archive.Add(config.File{
+				Source:      "testdata/foo.txt",
+				Destination: "foo.txt",
+			}))
+			require.NoError(t, archive.Close())
+
+			sha256sum := sha256.Sum256(buf.Bytes())
+			sha512sum := sha512.Sum512(buf.Bytes())
+			blake2bsum := blake2b.Sum256(buf.Bytes())
+			require.Equal(t, map[crypto.Hash][]byte{
+				crypto.SHA256:      sha256sum[:],
+				crypto.SHA512:      sha512sum[:],
+				crypto.BLAKE2b_256: blake2bsum[:],
+			}, hasher.Sums())
+		})
+	}
+
+	t.Run("not available", func(t *testing.T) {
+		_, err := NewHasher(crypto.MD4)
+		require.EqualError(t, err, "hash algorithm not available: MD4")
+	})
+}
+
+func TestHasherBLAKE3(t *testing.T) {
+	hasher, err := NewHasher(crypto.SHA256)
+	require.NoError(t, err)
+	require.Nil(t, hasher.BLAKE3Sum())
+	hasher.UseBLAKE3()
+	require.Equal(t, "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262", hex.EncodeToString(hasher.BLAKE3Sum()))
+	_, err = hasher.Write([]byte("abc"))
+	require.NoError(t, err)
+	require.Equal(t, "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85", hex.EncodeToString(hasher.BLAKE3Sum()))
+
+	t.Run("archive", func(t *testing.T) {
+		hasher, err := NewHasher()
+		require.NoError(t, err)
+		hasher.UseBLAKE3()
+
+		var buf bytes.Buffer
+		archive, err := New(&buf, "tar.gz", WithHasher(hasher))
+		require.NoError(t, err)
+		require.NoError(t, archive.Add(config.File{
+			Source:      "testdata/foo.txt",
+			Destination: "foo.txt",
+		}))
+		require.NoError(t, archive.Close())
+
+		sum := blake3.Sum256(buf.Bytes())
+		require.Equal(t, sum[:], hasher.BLAKE3Sum())
+		require.Empty(t, hasher.Sums())
+	})
+}
@@ -0,0 +1,34 @@
+// Package closed keeps track of whether archives were closed.
+package closed
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// ErrClosed happens when an archive is used after being closed.
+var ErrClosed = errors.New("archive already closed")
+
+// Flag tracks whether an archive was closed.
+type Flag struct {
+	closed bool
+}
+
+// Close marks the archive as closed, failing with [ErrClosed] if it already
+// was.
+func (f *Flag) Close() error {
+	if f.closed {
+		return ErrClosed
+	}
+	f.closed = true
+	return nil
+}
+
+// Check fails with [ErrClosed] if the archive was closed, in which case the
+// given destination can't be added to it.
+func (f *Flag) Check(dst string) error {
+	if f.closed {
+		return &fs.PathError{Err: ErrClosed, Path: dst, Op: "add"}
+	}
+	return nil
+}
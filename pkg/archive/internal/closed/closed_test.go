@@ -0,0 +1,16 @@
+package closed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlag(t *testing.T) {
+	var f Flag
+	require.NoError(t, f.Check("foo.txt"))
+	require.NoError(t, f.Close())
+	require.EqualError(t, f.Check("foo.txt"), "add foo.txt: archive already closed")
+	require.ErrorIs(t, f.Check("foo.txt"), ErrClosed)
+	require.ErrorIs(t, f.Close(), ErrClosed)
+}
@@ -0,0 +1,30 @@
+// Package copybuf copies file contents into archives using pooled buffers.
+package copybuf
+
+import (
+	"io"
+	"sync"
+)
+
+// pools holds a *sync.Pool of *[]byte for each buffer size in use.
+var pools sync.Map
+
+// Copy copies src to dst using a buffer of the given size, taken from a pool.
+// If size is not positive, it works like [io.Copy].
+func Copy(dst io.Writer, src io.Reader, size int) (int64, error) {
+	if size <= 0 {
+		return io.Copy(dst, src)
+	}
+	p, _ := pools.LoadOrStore(size, &sync.Pool{
+		New: func() any {
+			buf := make([]byte, size)
+			return &buf
+		},
+	})
+	pool := p.(*sync.Pool)
+	buf := pool.Get().(*[]byte)
+	defer pool.Put(buf)
+	// hides implementations of io.WriterTo, like *os.File, which would
+	// otherwise ignore the buffer.
+	return io.CopyBuffer(dst, struct{ io.Reader }{src}, *buf)
+}
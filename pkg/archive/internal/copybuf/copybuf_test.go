@@ -0,0 +1,36 @@
+package copybuf
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopy(t *testing.T) {
+	content := make([]byte, 100_000)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(content)
+
+	for _, size := range []int{0, -1, 1, 7, 4096, 1 << 20} {
+		var out recordingWriter
+		n, err := Copy(&out, bytes.NewReader(content), size)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(content)), n)
+		require.Equal(t, content, out.buf.Bytes())
+		if size > 0 {
+			require.LessOrEqual(t, out.largest, size)
+		}
+	}
+}
+
+// recordingWriter keeps track of the largest write.
+type recordingWriter struct {
+	buf     bytes.Buffer
+	largest int
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.largest = max(w.largest, len(p))
+	return w.buf.Write(p)
+}
@@ -0,0 +1,16 @@
+// Package counting counts the bytes written to archives.
+package counting
+
+import "io"
+
+// Writer counts the bytes actually written to W.
+type Writer struct {
+	W io.Writer
+	N int64
+}
+
+func (c *Writer) Write(p []byte) (int, error) {
+	n, err := c.W.Write(p)
+	c.N += int64(n)
+	return n, err
+}
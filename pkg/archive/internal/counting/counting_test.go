@@ -0,0 +1,41 @@
+package counting
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &Writer{W: &buf}
+	_, err := w.Write([]byte("foo"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("bar"))
+	require.NoError(t, err)
+	require.Equal(t, int64(6), w.N)
+
+	t.Run("short write", func(t *testing.T) {
+		w := &Writer{W: shortWriter{limit: 3}}
+		n, err := w.Write([]byte("foobar"))
+		require.ErrorIs(t, err, errShortWrite)
+		require.Equal(t, 3, n)
+		require.Equal(t, int64(3), w.N)
+	})
+}
+
+var errShortWrite = errors.New("short write")
+
+// shortWriter only accepts the first limit bytes of each write.
+type shortWriter struct {
+	limit int
+}
+
+func (w shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		return w.limit, errShortWrite
+	}
+	return len(p), nil
+}
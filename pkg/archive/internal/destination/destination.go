@@ -0,0 +1,111 @@
+// Package destination provides helpers to validate the destination of files
+// added to archives.
+package destination
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsafe happens when a destination would be extracted outside of the
+// archive root.
+var ErrUnsafe = errors.New("path escapes the archive root")
+
+// Validate checks that the given destination, once cleaned, is relative and
+// does not escape the archive root.
+func Validate(name string) error {
+	clean := path.Clean(filepath.ToSlash(name))
+	if filepath.IsAbs(name) ||
+		path.IsAbs(clean) ||
+		clean == ".." ||
+		strings.HasPrefix(clean, "../") {
+		return &fs.PathError{Err: ErrUnsafe, Path: name, Op: "add"}
+	}
+	return nil
+}
+
+// ValidateLink checks that the target of the symlink at the given
+// destination is relative and, once resolved, does not escape the archive
+// root.
+func ValidateLink(name, target string) error {
+	target = filepath.ToSlash(target)
+	resolved := path.Join(path.Dir(name), target)
+	if path.IsAbs(target) ||
+		filepath.IsAbs(target) ||
+		resolved == ".." ||
+		strings.HasPrefix(resolved, "../") {
+		return &fs.PathError{
+			Err:  fmt.Errorf("link to %q: %w", target, ErrUnsafe),
+			Path: name,
+			Op:   "add",
+		}
+	}
+	return nil
+}
+
+// SafeLink rewrites the target of the symlink at the given destination to a
+// relative one which does not escape the archive root, as if the root was the
+// file system root: absolute targets are made relative to it, and ".."
+// components which would go above it are dropped.
+func SafeLink(name, target string) string {
+	target = filepath.ToSlash(target)
+	if filepath.IsAbs(target) {
+		// e.g. C:/foo, which can't be made relative in a portable way.
+		target = "/" + strings.TrimPrefix(target, filepath.VolumeName(target))
+	}
+	dir := path.Dir(path.Clean("/" + name))
+	resolved := target
+	if !path.IsAbs(target) {
+		resolved = path.Join(dir, target)
+	}
+	return relative(dir, path.Clean(resolved))
+}
+
+// relative returns the relative path from the directory from to the path to,
+// both of which must be absolute and clean.
+func relative(from, to string) string {
+	fromParts := strings.Split(strings.TrimPrefix(from, "/"), "/")
+	toParts := strings.Split(strings.TrimPrefix(to, "/"), "/")
+	if fromParts[0] == "" {
+		fromParts = nil
+	}
+	if toParts[0] == "" {
+		toParts = nil
+	}
+	i := 0
+	for i < len(fromParts) && i < len(toParts) && fromParts[i] == toParts[i] {
+		i++
+	}
+	parts := make([]string, 0, len(fromParts)-i+len(toParts)-i)
+	for range fromParts[i:] {
+		parts = append(parts, "..")
+	}
+	parts = append(parts, toParts[i:]...)
+	if len(parts) == 0 {
+		return "."
+	}
+	return path.Join(parts...)
+}
+
+// Folded keeps track of destinations in a case-insensitive manner, mapping
+// the case-folded destination to the original one.
+type Folded map[string]string
+
+// Add records the given destination, failing if another destination which
+// differs from it only by case was already added.
+func (f Folded) Add(name string) error {
+	key := strings.ToLower(name)
+	if prev, ok := f[key]; ok {
+		return &fs.PathError{
+			Err:  fmt.Errorf("conflicts with %q on case-insensitive file systems: %w", prev, fs.ErrExist),
+			Path: name,
+			Op:   "add",
+		}
+	}
+	f[key] = name
+	return nil
+}
@@ -0,0 +1,96 @@
+package destination
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	for _, name := range []string{
+		"foo.txt",
+		"sub1/bar.txt",
+		"./foo.txt",
+		"sub1/../foo.txt",
+		"..foo.txt",
+		"ملف.txt",
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, Validate(name))
+		})
+	}
+
+	for _, name := range []string{
+		"..",
+		"../foo.txt",
+		"../../etc/passwd",
+		"sub1/../../foo.txt",
+		"/etc/passwd",
+		"/",
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.ErrorIs(t, Validate(name), ErrUnsafe)
+		})
+	}
+}
+
+func TestFolded(t *testing.T) {
+	folded := Folded{}
+	require.NoError(t, folded.Add("README.md"))
+	require.NoError(t, folded.Add("LICENSE"))
+	require.ErrorIs(t, folded.Add("readme.md"), fs.ErrExist)
+	require.EqualError(t, folded.Add("License"), `add License: conflicts with "LICENSE" on case-insensitive file systems: file already exists`)
+}
+
+func TestValidateLink(t *testing.T) {
+	for _, tt := range [][2]string{
+		{"link", "foo.txt"},
+		{"bin/link", "../lib/foo.so"},
+		{"bin/link", "./foo"},
+		{"a/b/link", "../../foo.txt"},
+		{"link", "."},
+	} {
+		t.Run(tt[0]+" -> "+tt[1], func(t *testing.T) {
+			require.NoError(t, ValidateLink(tt[0], tt[1]))
+		})
+	}
+
+	for _, tt := range [][2]string{
+		{"link", "../foo.txt"},
+		{"bin/link", "../../etc/passwd"},
+		{"link", ".."},
+		{"link", "/etc/passwd"},
+		{"a/link", "b/../../../foo"},
+	} {
+		t.Run(tt[0]+" -> "+tt[1], func(t *testing.T) {
+			require.ErrorIs(t, ValidateLink(tt[0], tt[1]), ErrUnsafe)
+		})
+	}
+
+	require.EqualError(t, ValidateLink("bin/link", "/etc/passwd"), `add bin/link: link to "/etc/passwd": path escapes the archive root`)
+}
+
+func TestSafeLink(t *testing.T) {
+	for _, tt := range []struct {
+		name, target, expected string
+	}{
+		{"link", "foo.txt", "foo.txt"},
+		{"bin/link", "../lib/foo.so", "../lib/foo.so"},
+		{"bin/link", "./foo", "foo"},
+		{"link", "../foo.txt", "foo.txt"},
+		{"bin/link", "../../etc/passwd", "../etc/passwd"},
+		{"link", "/etc/passwd", "etc/passwd"},
+		{"a/b/link", "/etc/passwd", "../../etc/passwd"},
+		{"a/b/link", "/a/b/c", "c"},
+		{"a/b/link", "/a", ".."},
+		{"a/link", "/", ".."},
+		{"link", "..", "."},
+	} {
+		t.Run(tt.name+" -> "+tt.target, func(t *testing.T) {
+			got := SafeLink(tt.name, tt.target)
+			require.Equal(t, tt.expected, got)
+			require.NoError(t, ValidateLink(tt.name, got))
+		})
+	}
+}
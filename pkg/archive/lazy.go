@@ -0,0 +1,64 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// Lazy wraps an archive, allowing files to be added before their sources
+// exist, with [Lazy.LazyAdd].
+type Lazy struct {
+	Archive
+	files []config.File
+}
+
+func (l *Lazy) unwrap() Archive { return l.Archive }
+
+// NewLazy wraps the given archive.
+func NewLazy(a Archive) *Lazy {
+	return &Lazy{Archive: a}
+}
+
+// AddWithReader adds the given regular file right away, reading its content
+// from r.
+func (l *Lazy) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	return addReader(l.Archive, f, info, r)
+}
+
+// LazyAdd records the given file, which is only added to the archive on
+// Close, after the files added with Add.
+func (l *Lazy) LazyAdd(f config.File) {
+	l.files = append(l.files, f)
+}
+
+// Close adds all the files recorded by LazyAdd, and closes the archive.
+//
+// All sources are checked before anything is added, and, if any of them is
+// missing, they are all reported together.
+func (l *Lazy) Close() error {
+	var errs []error
+	for _, f := range l.files {
+		if f.Source == "" {
+			continue
+		}
+		if _, err := os.Lstat(f.Source); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.Destination, err))
+		}
+	}
+	if len(errs) > 0 {
+		_ = l.Archive.Close()
+		return fmt.Errorf("missing sources: %w", errors.Join(errs...))
+	}
+	for _, f := range l.files {
+		if err := l.Archive.Add(f); err != nil {
+			_ = l.Archive.Close()
+			return fmt.Errorf("could not add %s: %w", f.Source, err)
+		}
+	}
+	return l.Archive.Close()
+}
@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goreleaser/goreleaser/v2/internal/testlib"
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazy(t *testing.T) {
+	dir := t.TempDir()
+	generated := filepath.Join(dir, "generated.txt")
+
+	path := filepath.Join(dir, "archive.tar")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	archive, err := New(f, "tar")
+	require.NoError(t, err)
+	lazy := NewLazy(archive)
+	lazy.LazyAdd(config.File{
+		Source:      generated,
+		Destination: "generated.txt",
+	})
+	require.NoError(t, lazy.Add(config.File{
+		Source:      "testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+
+	// the source is only generated after being added.
+	require.NoError(t, os.WriteFile(generated, []byte("generated"), 0o644))
+	require.NoError(t, lazy.Close())
+	require.NoError(t, f.Close())
+	require.Equal(t, []string{"foo.txt", "generated.txt"}, testlib.LsArchive(t, path, "tar"))
+
+	t.Run("missing sources", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar")
+		require.NoError(t, err)
+		lazy := NewLazy(archive)
+		for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+			lazy.LazyAdd(config.File{
+				Source:      filepath.Join(dir, "missing", name),
+				Destination: name,
+			})
+		}
+		lazy.LazyAdd(config.File{
+			Source:      "testdata/foo.txt",
+			Destination: "foo.txt",
+		})
+		err = lazy.Close()
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		require.ErrorContains(t, err, "missing sources: ")
+		for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+			require.ErrorContains(t, err, name+": lstat "+filepath.Join(dir, "missing", name))
+		}
+		require.NotContains(t, err.Error(), "foo.txt")
+	})
+
+	t.Run("add error", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar")
+		require.NoError(t, err)
+		lazy := NewLazy(archive)
+		for range 2 {
+			lazy.LazyAdd(config.File{
+				Source:      "testdata/foo.txt",
+				Destination: "foo.txt",
+			})
+		}
+		require.ErrorIs(t, lazy.Close(), fs.ErrExist)
+	})
+}
@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/gzip"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/zip"
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// AddArchive creates an archive in the given format with the given files,
+// and adds it to a at the given destination, without writing it to the disk
+// when possible.
+//
+// Zip and gzip archives don't need to know the size of their entries up
+// front, so the inner archive is streamed into them, unless options like
+// [WithRejectEmptyFiles], [WithOnAdd], [WithStats] or [WithDuplicates] need
+// it.
+// Otherwise, the inner archive is created in a temporary file first, so it
+// is added with its actual size.
+//
+// The modification time of the inner archive is the latest one of its files,
+// or the current time if none is set.
+func AddArchive(a Archive, dst, format string, files []config.File) error {
+	info := readerInfo{
+		name:  path.Base(dst),
+		size:  -1,
+		mode:  0o644,
+		mtime: time.Now(),
+	}
+	var latest time.Time
+	for _, f := range files {
+		if f.Info.ParsedMTime.After(latest) {
+			latest = f.Info.ParsedMTime
+		}
+	}
+	if !latest.IsZero() {
+		info.mtime = latest
+	}
+	f := config.File{Destination: dst}
+
+	if streams(a) {
+		pr, pw := io.Pipe()
+		inner, err := New(pw, format)
+		if err != nil {
+			return err
+		}
+		done := make(chan error, 1)
+		go func() {
+			err := writeNested(inner, files)
+			_ = pw.CloseWithError(err)
+			done <- err
+		}()
+		err = addReader(a, f, info, pr)
+		// unblocks the inner archive if the entry failed before reading it.
+		_ = pr.Close()
+		if werr := <-done; werr != nil && !errors.Is(werr, io.ErrClosedPipe) {
+			return werr
+		}
+		return err
+	}
+
+	tmp, err := os.CreateTemp("", "goreleaser-nested-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	inner, err := New(tmp, format)
+	if err != nil {
+		return err
+	}
+	if err := writeNested(inner, files); err != nil {
+		return err
+	}
+	if info.size, err = tmp.Seek(0, io.SeekCurrent); err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return addReader(a, f, info, tmp)
+}
+
+// streams tells whether entries of an unknown size can be added to the given
+// archive, as neither its format nor its options need to know it up front.
+func streams(a Archive) bool {
+	for {
+		switch a.(type) {
+		case zip.Archive, gzip.Archive:
+			return true
+		case onAddArchive, rejectEmptyArchive, bytesInArchive, duplicatesArchive, *Delta:
+			return false
+		}
+		u, ok := a.(interface{ unwrap() Archive })
+		if !ok {
+			return false
+		}
+		a = u.unwrap()
+	}
+}
+
+func writeNested(a Archive, files []config.File) error {
+	for _, f := range files {
+		if err := a.Add(f); err != nil {
+			_ = a.Close()
+			return err
+		}
+	}
+	return a.Close()
+}
@@ -0,0 +1,169 @@
+package archive
+
+import (
+	"archive/tar"
+	stdzip "archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddArchive(t *testing.T) {
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	files := []config.File{
+		{Source: "testdata/foo.txt", Destination: "foo.txt"},
+		{
+			Source:      "testdata/sub1/bar.txt",
+			Destination: "sub1/bar.txt",
+			Info:        config.FileInfo{ParsedMTime: mtime},
+		},
+	}
+	inner := func(tb testing.TB, r io.Reader) map[string]string {
+		tb.Helper()
+		gr, err := gzip.NewReader(r)
+		require.NoError(tb, err)
+		contents := map[string]string{}
+		tr := tar.NewReader(gr)
+		for {
+			header, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				return contents
+			}
+			require.NoError(tb, err)
+			bts, err := io.ReadAll(tr)
+			require.NoError(tb, err)
+			contents[header.Name] = string(bts)
+		}
+	}
+	foo, err := os.ReadFile("testdata/foo.txt")
+	require.NoError(t, err)
+	bar, err := os.ReadFile("testdata/sub1/bar.txt")
+	require.NoError(t, err)
+	expected := map[string]string{
+		"foo.txt":      string(foo),
+		"sub1/bar.txt": string(bar),
+	}
+
+	for _, opts := range [][]Option{nil, {WithReproducible()}} {
+		t.Run("zip", func(t *testing.T) {
+			var buf bytes.Buffer
+			archive, err := New(&buf, "zip", opts...)
+			require.NoError(t, err)
+			require.NoError(t, AddArchive(archive, "bundle/inner.tar.gz", "tar.gz", files))
+			require.NoError(t, archive.Close())
+
+			r, err := stdzip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			require.NoError(t, err)
+			require.Len(t, r.File, 1)
+			require.Equal(t, "bundle/inner.tar.gz", r.File[0].Name)
+			require.True(t, mtime.Equal(r.File[0].Modified))
+			rc, err := r.File[0].Open()
+			require.NoError(t, err)
+			defer rc.Close()
+			require.Equal(t, expected, inner(t, rc))
+		})
+	}
+
+	t.Run("tar", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive, err := New(&buf, "tar")
+		require.NoError(t, err)
+		require.NoError(t, AddArchive(archive, "inner.tar.gz", "tar.gz", files))
+		require.NoError(t, archive.Close())
+
+		tr := tar.NewReader(&buf)
+		header, err := tr.Next()
+		require.NoError(t, err)
+		require.Equal(t, "inner.tar.gz", header.Name)
+		require.Equal(t, expected, inner(t, tr))
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive, err := New(&buf, "tar", WithDuplicates(&Duplicates{}))
+		require.NoError(t, err)
+		require.NoError(t, AddArchive(archive, "inner.tar.gz", "tar.gz", files))
+		require.NoError(t, archive.Close())
+
+		tr := tar.NewReader(&buf)
+		_, err = tr.Next()
+		require.NoError(t, err)
+		require.Equal(t, expected, inner(t, tr))
+	})
+
+	t.Run("zip size", func(t *testing.T) {
+		var size int64
+		archive, err := New(io.Discard, "zip", WithRejectEmptyFiles(), WithOnAdd(func(_ config.File, info fs.FileInfo) {
+			size = info.Size()
+		}))
+		require.NoError(t, err)
+		require.NoError(t, AddArchive(archive, "inner.tar.gz", "tar.gz", files))
+		require.NoError(t, archive.Close())
+		require.Positive(t, size)
+	})
+
+	t.Run("inner failure", func(t *testing.T) {
+		for _, format := range []string{"zip", "tar"} {
+			archive, err := New(io.Discard, format)
+			require.NoError(t, err)
+			err = AddArchive(archive, "inner.tar.gz", "tar.gz", []config.File{
+				{Source: filepath.Join(t.TempDir(), "nope.txt"), Destination: "nope.txt"},
+			})
+			require.ErrorIs(t, err, os.ErrNotExist)
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar")
+		require.NoError(t, err)
+		require.EqualError(t, AddArchive(archive, "inner.foo", "foo", files), "invalid archive format: foo")
+	})
+}
+
+func TestAddArchiveStreams(t *testing.T) {
+	for name, tc := range map[string]struct {
+		format  string
+		opts    []Option
+		streams bool
+	}{
+		"zip":              {format: "zip", streams: true},
+		"reproducible zip": {format: "zip", opts: []Option{WithReproducible(), WithPrefix("foo")}, streams: true},
+		"gz":               {format: "gz", streams: true},
+		"tar":              {format: "tar"},
+		"zip on add":       {format: "zip", opts: []Option{WithOnAdd(func(config.File, fs.FileInfo) {})}},
+		"zip stats":        {format: "zip", opts: []Option{WithStats()}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			archive, err := New(io.Discard, tc.format, tc.opts...)
+			require.NoError(t, err)
+			require.Equal(t, tc.streams, streams(archive))
+			require.NoError(t, archive.Close())
+		})
+	}
+
+	t.Run("gz", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive, err := New(&buf, "gz")
+		require.NoError(t, err)
+		require.NoError(t, AddArchive(archive, "inner.tar", "tar", []config.File{
+			{Source: "testdata/foo.txt", Destination: "foo.txt"},
+		}))
+		require.NoError(t, archive.Close())
+
+		gr, err := gzip.NewReader(&buf)
+		require.NoError(t, err)
+		require.Equal(t, "inner.tar", gr.Name)
+		header, err := tar.NewReader(gr).Next()
+		require.NoError(t, err)
+		require.Equal(t, "foo.txt", header.Name)
+	})
+}
@@ -0,0 +1,76 @@
+package archive
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// WithOnAdd makes the given function be called after each file is added,
+// e.g. to record what went into the archive, with the file as it was
+// actually added, after all other options were applied, and the info of the
+// entry, with its final size, mode and modification time.
+//
+// Files added from readers, e.g. with [AddFS], are reported without a
+// source.
+func WithOnAdd(fn func(f config.File, info fs.FileInfo)) Option {
+	return func(o *options) {
+		o.onAdd = fn
+	}
+}
+
+type onAddArchive struct {
+	Archive
+	fn func(f config.File, info fs.FileInfo)
+}
+
+func (a onAddArchive) unwrap() Archive { return a.Archive }
+
+func (a onAddArchive) Add(f config.File) error {
+	if err := a.Archive.Add(f); err != nil {
+		return err
+	}
+	var src fs.FileInfo
+	if f.Source != "" {
+		if info, err := os.Lstat(f.Source); err == nil {
+			src = info
+		}
+	}
+	a.report(f, src)
+	return nil
+}
+
+func (a onAddArchive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	if err := addReader(a.Archive, f, info, r); err != nil {
+		return err
+	}
+	a.report(f, info)
+	return nil
+}
+
+// report calls the callback with the info of the added entry, from the file
+// info and the info of its content, if known.
+func (a onAddArchive) report(f config.File, src fs.FileInfo) {
+	info := readerInfo{
+		name:  path.Base(f.Destination),
+		mode:  f.Info.Mode,
+		mtime: f.Info.ParsedMTime,
+	}
+	if src != nil {
+		if !src.IsDir() {
+			info.size = src.Size()
+		}
+		if f.Info.Mode == 0 {
+			info.mode = src.Mode()
+		} else {
+			info.mode |= src.Mode().Type()
+		}
+		if info.mtime.IsZero() {
+			info.mtime = src.ModTime()
+		}
+	}
+	a.fn(f, info)
+}
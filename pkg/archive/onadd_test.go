@@ -0,0 +1,69 @@
+package archive
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnAdd(t *testing.T) {
+	type added struct {
+		size int64
+		mode fs.FileMode
+	}
+	for _, format := range []string{"tar.gz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			entries := map[string]added{}
+			calls := 0
+			archive, err := New(io.Discard, format,
+				WithPrefix("app"),
+				WithPermMask(0o755),
+				WithOnAdd(func(f config.File, info fs.FileInfo) {
+					calls++
+					entries[f.Destination] = added{size: info.Size(), mode: info.Mode()}
+					if f.Destination == "app/foo.txt" {
+						require.True(t, mtime.Equal(info.ModTime()))
+					}
+				}),
+			)
+			require.NoError(t, err)
+
+			foo, err := os.Stat("testdata/foo.txt")
+			require.NoError(t, err)
+			require.NoError(t, archive.Add(config.File{
+				Source:      "testdata/foo.txt",
+				Destination: "foo.txt",
+				Info: config.FileInfo{
+					Mode:        0o777,
+					ParsedMTime: mtime,
+				},
+			}))
+			require.NoError(t, archive.Add(config.File{
+				Destination: "logs",
+				Info:        config.FileInfo{Mode: fs.ModeDir | 0o755},
+			}))
+			require.Error(t, archive.Add(config.File{
+				Source:      "testdata/foo.txt",
+				Destination: "foo.txt",
+			}))
+			require.NoError(t, AddFS(archive, fstest.MapFS{
+				"bar.txt": {Data: []byte("bar"), Mode: 0o666},
+			}, "fs"))
+			require.NoError(t, archive.Close())
+
+			require.Equal(t, 3, calls)
+			require.Equal(t, map[string]added{
+				"app/foo.txt":    {size: foo.Size(), mode: 0o755},
+				"app/logs":       {mode: fs.ModeDir | 0o755},
+				"app/fs/bar.txt": {size: 3, mode: 0o644},
+			}, entries)
+		})
+	}
+}
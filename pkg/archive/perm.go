@@ -0,0 +1,112 @@
+package archive
+
+import (
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// WithPermMask makes the permissions of all entries be masked with the given
+// mask, e.g. 0o755 strips the group and other write bits, as well as the
+// setuid, setgid and sticky bits, which are only kept if they are in the mask.
+//
+// The special bits can be given either as [fs.ModeSetuid], [fs.ModeSetgid] and
+// [fs.ModeSticky], or as the raw unix 0o4000, 0o2000 and 0o1000 bits, in both
+// the mask and the file modes, e.g. a 0o4755 mask keeps the setuid bit of a
+// file with a [fs.ModeSetuid] mode.
+func WithPermMask(mask fs.FileMode) Option {
+	return func(o *options) {
+		o.permMask = mask
+	}
+}
+
+// permBits are the bits of a mode which are masked: the permissions, and the
+// special bits.
+const permBits = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+// goMode converts the raw unix setuid, setgid and sticky bits of the given
+// mode to their [fs.FileMode] counterparts.
+func goMode(mode fs.FileMode) fs.FileMode {
+	m := mode &^ 0o7000
+	if mode&0o4000 != 0 {
+		m |= fs.ModeSetuid
+	}
+	if mode&0o2000 != 0 {
+		m |= fs.ModeSetgid
+	}
+	if mode&0o1000 != 0 {
+		m |= fs.ModeSticky
+	}
+	return m
+}
+
+// maskMode masks the permissions of the given mode, keeping its type.
+func maskMode(mode, mask fs.FileMode) fs.FileMode {
+	mode = goMode(mode)
+	return mode&^permBits | mode&permBits&goMode(mask)
+}
+
+type permMaskArchive struct {
+	Archive
+	mask fs.FileMode
+}
+
+func (a permMaskArchive) unwrap() Archive { return a.Archive }
+
+func (a permMaskArchive) Add(f config.File) error {
+	var src fs.FileInfo
+	if f.Info.Mode == 0 && f.Source != "" {
+		if info, err := os.Lstat(f.Source); err == nil {
+			src = info
+		}
+	}
+	mode := a.maskedMode(f, src)
+	if mode != 0 || src == nil || !src.Mode().IsRegular() {
+		f.Info.Mode = mode
+		return a.Archive.Add(f)
+	}
+	// a zero mode means the one of the source is used, so the file is added
+	// from a reader instead, with its masked mode in its info.
+	file, err := os.Open(f.Source) // #nosec
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return addReader(a.Archive, f, maskedInfo{FileInfo: info}, file)
+}
+
+func (a permMaskArchive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	mode := a.maskedMode(f, info)
+	if mode == 0 {
+		f.Info.Mode = 0
+		return addReader(a.Archive, f, maskedInfo{FileInfo: info}, r)
+	}
+	f.Info.Mode = mode
+	return addReader(a.Archive, f, info, r)
+}
+
+// maskedMode returns the masked mode of the given file, which defaults to the
+// one of its source, if known.
+func (a permMaskArchive) maskedMode(f config.File, src fs.FileInfo) fs.FileMode {
+	mode := f.Info.Mode
+	if mode == 0 && src != nil {
+		mode = src.Mode()
+	}
+	if mode == 0 {
+		return 0
+	}
+	return maskMode(mode, a.mask)
+}
+
+// maskedInfo is the info of a regular file whose permissions were all masked.
+type maskedInfo struct {
+	fs.FileInfo
+}
+
+func (maskedInfo) Mode() fs.FileMode { return 0 }
@@ -0,0 +1,204 @@
+package archive
+
+import (
+	"archive/tar"
+	stdzip "archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermMask(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "app")
+	require.NoError(t, os.WriteFile(src, []byte("app"), 0o644))
+	require.NoError(t, os.Chmod(src, fs.ModeSetuid|0o777))
+	info, err := os.Stat(src)
+	require.NoError(t, err)
+	if info.Mode() != fs.ModeSetuid|0o777 {
+		t.Skip("setuid bit not supported")
+	}
+
+	add := func(tb testing.TB, a Archive) {
+		tb.Helper()
+		require.NoError(tb, a.Add(config.File{Source: src, Destination: "bin/app"}))
+		require.NoError(tb, a.Add(config.File{
+			Source:      src,
+			Destination: "bin/explicit",
+			Info:        config.FileInfo{Mode: 0o4777},
+		}))
+		require.NoError(tb, a.Add(config.File{
+			Destination: "share",
+			Info:        config.FileInfo{Mode: fs.ModeDir | 0o777},
+		}))
+		require.NoError(tb, AddFS(a, fstest.MapFS{
+			"tool": {Data: []byte("tool"), Mode: fs.ModeSetgid | 0o775},
+		}, "fs"))
+		require.NoError(tb, a.Close())
+	}
+
+	t.Run("tar", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			mask     fs.FileMode
+			expected map[string]int64
+		}{
+			"plain": {
+				mask: 0o755,
+				expected: map[string]int64{
+					"bin/app":      0o755,
+					"bin/explicit": 0o755,
+					"share/":       0o755,
+					"fs/tool":      0o755,
+				},
+			},
+			"go special bits": {
+				mask: fs.ModeSetuid | fs.ModeSetgid | 0o755,
+				expected: map[string]int64{
+					"bin/app":      0o4755,
+					"bin/explicit": 0o4755,
+					"share/":       0o755,
+					"fs/tool":      0o2755,
+				},
+			},
+			"unix special bits": {
+				mask: 0o4755,
+				expected: map[string]int64{
+					"bin/app":      0o4755,
+					"bin/explicit": 0o4755,
+					"share/":       0o755,
+					"fs/tool":      0o755,
+				},
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				var buf bytes.Buffer
+				archive, err := New(&buf, "tar", WithPermMask(tc.mask))
+				require.NoError(t, err)
+				add(t, archive)
+
+				modes := map[string]int64{}
+				r := tar.NewReader(&buf)
+				for {
+					header, err := r.Next()
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					require.NoError(t, err)
+					modes[header.Name] = header.Mode
+				}
+				require.Equal(t, tc.expected, modes)
+			})
+		}
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive, err := New(&buf, "zip", WithPermMask(0o755))
+		require.NoError(t, err)
+		add(t, archive)
+
+		r, err := stdzip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		modes := map[string]fs.FileMode{}
+		for _, f := range r.File {
+			modes[f.Name] = f.Mode()
+		}
+		require.Equal(t, map[string]fs.FileMode{
+			"bin/app":      0o755,
+			"bin/explicit": 0o755,
+			"share/":       fs.ModeDir | 0o755,
+			"fs/tool":      0o755,
+		}, modes)
+	})
+}
+
+func TestPermMaskTypeAndZero(t *testing.T) {
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "app")
+	require.NoError(t, os.WriteFile(src, []byte("app"), 0o644))
+	link := filepath.Join(tmp, "link")
+	require.NoError(t, os.Symlink("app", link))
+
+	add := func(tb testing.TB, a Archive) {
+		tb.Helper()
+		require.NoError(tb, a.Add(config.File{Source: src, Destination: "app"}))
+		require.NoError(tb, a.Add(config.File{Source: link, Destination: "link"}))
+		require.NoError(tb, a.Close())
+	}
+
+	t.Run("tar", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive, err := New(&buf, "tar", WithPermMask(0o1000))
+		require.NoError(t, err)
+		add(t, archive)
+
+		r := tar.NewReader(&buf)
+		header, err := r.Next()
+		require.NoError(t, err)
+		require.Equal(t, "app", header.Name)
+		require.Equal(t, byte(tar.TypeReg), header.Typeflag)
+		require.Equal(t, int64(0), header.Mode)
+		header, err = r.Next()
+		require.NoError(t, err)
+		require.Equal(t, "link", header.Name)
+		require.Equal(t, byte(tar.TypeSymlink), header.Typeflag)
+		require.Equal(t, "app", header.Linkname)
+		require.Equal(t, int64(0), header.Mode)
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive, err := New(&buf, "zip", WithPermMask(0o755))
+		require.NoError(t, err)
+		add(t, archive)
+
+		r, err := stdzip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		modes := map[string]fs.FileMode{}
+		for _, f := range r.File {
+			modes[f.Name] = f.Mode()
+		}
+		require.Equal(t, map[string]fs.FileMode{
+			"app":  0o644,
+			"link": fs.ModeSymlink | 0o755,
+		}, modes)
+	})
+
+	t.Run("zip fully masked", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive, err := New(&buf, "zip", WithPermMask(0o1000))
+		require.NoError(t, err)
+		add(t, archive)
+
+		r, err := stdzip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		modes := map[string]fs.FileMode{}
+		for _, f := range r.File {
+			modes[f.Name] = f.Mode()
+		}
+		require.Equal(t, map[string]fs.FileMode{
+			"app":  0,
+			"link": fs.ModeSymlink,
+		}, modes)
+	})
+
+	t.Run("on add", func(t *testing.T) {
+		modes := map[string]fs.FileMode{}
+		archive, err := New(io.Discard, "tar", WithPermMask(0o1000), WithOnAdd(func(f config.File, info fs.FileInfo) {
+			modes[f.Destination] = info.Mode()
+		}))
+		require.NoError(t, err)
+		add(t, archive)
+		require.Equal(t, map[string]fs.FileMode{
+			"app":  0,
+			"link": fs.ModeSymlink,
+		}, modes)
+	})
+}
@@ -0,0 +1,44 @@
+package archive
+
+import (
+	"io"
+	"io/fs"
+	"path"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/destination"
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// WithPrefix makes every entry be added inside the given directory, e.g.
+// "myapp_1.2.3", which is prepended to their destinations.
+//
+// Destinations are validated before being prefixed, so they can't escape it.
+// An empty prefix does nothing.
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+type prefixArchive struct {
+	Archive
+	prefix string
+}
+
+func (a prefixArchive) unwrap() Archive { return a.Archive }
+
+func (a prefixArchive) Add(f config.File) error {
+	if err := destination.Validate(f.Destination); err != nil {
+		return err
+	}
+	f.Destination = path.Join(a.prefix, f.Destination)
+	return a.Archive.Add(f)
+}
+
+func (a prefixArchive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	if err := destination.Validate(f.Destination); err != nil {
+		return err
+	}
+	f.Destination = path.Join(a.prefix, f.Destination)
+	return addReader(a.Archive, f, info, r)
+}
@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goreleaser/goreleaser/v2/internal/testlib"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/destination"
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPrefix(t *testing.T) {
+	for _, format := range []string{"tar", "tar.gz", "tar.xz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			for prefix, want := range map[string][]string{
+				"myapp_1.2.3": {
+					"myapp_1.2.3/foo.txt",
+					"myapp_1.2.3/sub1/bar.txt",
+					"myapp_1.2.3/fs/app.yml",
+				},
+				"": {
+					"foo.txt",
+					"sub1/bar.txt",
+					"fs/app.yml",
+				},
+			} {
+				path := filepath.Join(t.TempDir(), "archive."+format)
+				f, err := os.Create(path)
+				require.NoError(t, err)
+				archive, err := New(f, format, WithPrefix(prefix))
+				require.NoError(t, err)
+				require.NoError(t, archive.Add(config.File{
+					Source:      "testdata/foo.txt",
+					Destination: "foo.txt",
+				}))
+				require.NoError(t, archive.Add(config.File{
+					Source:      "testdata/sub1/bar.txt",
+					Destination: "sub1/bar.txt",
+				}))
+				require.NoError(t, AddFS(archive, fstest.MapFS{
+					"app.yml": {Data: []byte("a: b"), Mode: 0o644},
+				}, "fs"))
+				require.NoError(t, archive.Close())
+				require.NoError(t, f.Close())
+				require.Equal(t, want, testlib.LsArchive(t, path, format))
+			}
+		})
+	}
+
+	t.Run("gz", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "foo.txt.gz")
+		f, err := os.Create(path)
+		require.NoError(t, err)
+		archive, err := New(f, "gz", WithPrefix("myapp"))
+		require.NoError(t, err)
+		require.NoError(t, archive.Add(config.File{
+			Source:      "testdata/foo.txt",
+			Destination: "foo.txt",
+		}))
+		require.NoError(t, archive.Close())
+		require.NoError(t, f.Close())
+		require.Equal(t, []string{"myapp/foo.txt"}, testlib.LsArchive(t, path, "gz"))
+	})
+
+	t.Run("escaping the prefix", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar", WithPrefix("myapp"))
+		require.NoError(t, err)
+		err = archive.Add(config.File{
+			Source:      "testdata/foo.txt",
+			Destination: "../foo.txt",
+		})
+		require.ErrorIs(t, err, destination.ErrUnsafe)
+		var perr *fs.PathError
+		require.ErrorAs(t, err, &perr)
+		require.Equal(t, "../foo.txt", perr.Path)
+		require.ErrorIs(t, AddFS(archive, fstest.MapFS{}, "../fs"), destination.ErrUnsafe)
+		require.NoError(t, archive.Close())
+	})
+}
@@ -0,0 +1,38 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goreleaser/goreleaser/v2/internal/testlib"
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardSlashes(t *testing.T) {
+	for _, format := range []string{"tar", "tar.gz", "tar.xz", "tar.zst", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "archive."+format)
+			f, err := os.Create(path)
+			require.NoError(t, err)
+			archive, err := New(f, format)
+			require.NoError(t, err)
+			require.NoError(t, archive.Add(config.File{
+				Source:      "testdata/foo.txt",
+				Destination: filepath.Join("bin", "sub", "foo.txt"),
+			}))
+			require.NoError(t, AddFS(archive, fstest.MapFS{
+				"bar.txt": {Data: []byte("bar")},
+			}, filepath.Join("share", "doc")))
+			require.NoError(t, archive.Close())
+			require.NoError(t, f.Close())
+
+			require.ElementsMatch(t, []string{
+				"bin/sub/foo.txt",
+				"share/doc/bar.txt",
+			}, testlib.LsArchive(t, path, format))
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package archive
+
+import (
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/counting"
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// Stats holds how many bytes of file contents were added to an archive, and
+// how many bytes the archive itself took, which helps choosing compression
+// settings.
+type Stats struct {
+	// BytesIn is the total size of the regular files added.
+	BytesIn int64
+
+	// BytesOut is the number of bytes written to the archive output.
+	BytesOut int64
+}
+
+// Ratio returns the compression ratio, e.g. 0.25 if the archive is a quarter
+// of the size of its contents, or 0 if nothing was added.
+func (s Stats) Ratio() float64 {
+	if s.BytesIn == 0 {
+		return 0
+	}
+	return float64(s.BytesOut) / float64(s.BytesIn)
+}
+
+// StatsReporter is implemented by the archives created by [New] with
+// [WithStats].
+// Stats are only final once the archive is closed.
+type StatsReporter interface {
+	Stats() Stats
+}
+
+// WithStats makes the archive created by [New] implement [StatsReporter].
+//
+// Along with [WithHasher], the size and checksums of the archive are known
+// as soon as it is closed, without reading it again.
+func WithStats() Option {
+	return func(o *options) {
+		o.stats = true
+	}
+}
+
+type statsArchive struct {
+	Archive
+	out *counting.Writer
+	in  *int64
+}
+
+func (a statsArchive) unwrap() Archive { return a.Archive }
+
+func (a statsArchive) Stats() Stats {
+	return Stats{
+		BytesIn:  *a.in,
+		BytesOut: a.out.N,
+	}
+}
+
+func (a statsArchive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	return addReader(a.Archive, f, info, r)
+}
+
+// bytesInArchive counts the bytes of the regular files actually added to the
+// archive, after all the other options were applied, e.g. once the content
+// of URLs is downloaded and duplicates are skipped.
+type bytesInArchive struct {
+	Archive
+	n *int64
+}
+
+func (a bytesInArchive) unwrap() Archive { return a.Archive }
+
+func (a bytesInArchive) Add(f config.File) error {
+	var size int64
+	if f.Source != "" {
+		// symlinks are added as such, not with the content of their target.
+		if info, err := os.Lstat(f.Source); err == nil && info.Mode().IsRegular() {
+			size = info.Size()
+		}
+	}
+	if err := a.Archive.Add(f); err != nil {
+		return err
+	}
+	*a.n += size
+	return nil
+}
+
+func (a bytesInArchive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	if err := addReader(a.Archive, f, info, r); err != nil {
+		return err
+	}
+	*a.n += info.Size()
+	return nil
+}
+
+// statsForwarder makes an archive wrapping one created with [WithStats]
+// implement [StatsReporter] too.
+type statsForwarder struct {
+	Archive
+	StatsReporter
+}
+
+// forwardStats returns a, implementing [StatsReporter] if inner does.
+func forwardStats(a, inner Archive) Archive {
+	if s, ok := inner.(StatsReporter); ok {
+		return statsForwarder{Archive: a, StatsReporter: s}
+	}
+	return a
+}
+
+func (a statsForwarder) unwrap() Archive { return a.Archive }
+
+func (a statsForwarder) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	return addReader(a.Archive, f, info, r)
+}
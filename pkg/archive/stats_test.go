@@ -0,0 +1,124 @@
+package archive
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	for _, format := range []string{"tar", "tar.gz", "tar.xz", "tar.zst", "zip", "ar", "cpio"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			hasher, err := NewHasher(crypto.SHA256)
+			require.NoError(t, err)
+			archive, err := New(&buf, format, WithStats(), WithHasher(hasher))
+			require.NoError(t, err)
+			require.NoError(t, archive.Add(config.File{
+				Source:      "testdata/foo.txt",
+				Destination: "foo.txt",
+			}))
+			require.Error(t, archive.Add(config.File{
+				Source:      "testdata/foo.txt",
+				Destination: "foo.txt",
+			}))
+			require.NoError(t, AddFS(archive, fstest.MapFS{
+				"big.txt": {Data: []byte(strings.Repeat("a", 10_000)), Mode: 0o644},
+			}, ""))
+			require.NoError(t, archive.Close())
+
+			info, err := os.Stat("testdata/foo.txt")
+			require.NoError(t, err)
+			stats := archive.(StatsReporter).Stats()
+			require.Equal(t, info.Size()+10_000, stats.BytesIn)
+			require.NotZero(t, stats.BytesOut)
+			require.Equal(t, int64(buf.Len()), stats.BytesOut)
+			sum := sha256.Sum256(buf.Bytes())
+			require.Equal(t, sum[:], hasher.Sums()[crypto.SHA256])
+			require.InDelta(t, float64(stats.BytesOut)/float64(stats.BytesIn), stats.Ratio(), 0.0001)
+			if format != "tar" && format != "ar" && format != "cpio" {
+				require.Less(t, stats.Ratio(), 1.0)
+			}
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar.gz", WithStats())
+		require.NoError(t, err)
+		require.NoError(t, archive.Close())
+		stats := archive.(StatsReporter).Stats()
+		require.Zero(t, stats.BytesIn)
+		require.NotZero(t, stats.BytesOut)
+		require.Zero(t, stats.Ratio())
+	})
+
+	t.Run("symlinks", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar", WithStats())
+		require.NoError(t, err)
+		require.NoError(t, archive.Add(config.File{
+			Source:      "testdata/link.txt",
+			Destination: "link.txt",
+		}))
+		require.NoError(t, archive.Close())
+		require.Zero(t, archive.(StatsReporter).Stats().BytesIn)
+	})
+
+	t.Run("urls", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, "some payload")
+		}))
+		t.Cleanup(srv.Close)
+
+		archive, err := New(io.Discard, "tar", WithStats(), WithURLSources(srv.Client()))
+		require.NoError(t, err)
+		require.NoError(t, archive.Add(config.File{
+			Source:      srv.URL + "/payload",
+			Destination: "payload",
+		}))
+		require.NoError(t, archive.Close())
+		require.Equal(t, int64(len("some payload")), archive.(StatsReporter).Stats().BytesIn)
+	})
+
+	t.Run("wrapped", func(t *testing.T) {
+		concurrent, err := NewConcurrent(io.Discard, "tar", WithStats())
+		require.NoError(t, err)
+		volumes, err := NewMultiVolume(filepath.Join(t.TempDir(), "archive"), "tar", 1024, WithStats())
+		require.NoError(t, err)
+		delta, err := NewDelta(io.Discard, "tar", Manifest{}, WithStats())
+		require.NoError(t, err)
+		info, err := os.Stat("testdata/foo.txt")
+		require.NoError(t, err)
+		for _, archive := range []Archive{concurrent, volumes, delta} {
+			require.NoError(t, archive.Add(config.File{
+				Source:      "testdata/foo.txt",
+				Destination: "foo.txt",
+			}))
+			require.NoError(t, archive.Close())
+		}
+		require.Equal(t, info.Size(), concurrent.(StatsReporter).Stats().BytesIn)
+		require.Equal(t, info.Size(), volumes.(StatsReporter).Stats().BytesIn)
+		require.Equal(t, info.Size(), delta.Archive.(StatsReporter).Stats().BytesIn)
+
+		concurrent, err = NewConcurrent(io.Discard, "tar")
+		require.NoError(t, err)
+		require.NotImplements(t, (*StatsReporter)(nil), concurrent)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar.gz")
+		require.NoError(t, err)
+		require.NoError(t, archive.Close())
+		require.NotImplements(t, (*StatsReporter)(nil), archive)
+	})
+}
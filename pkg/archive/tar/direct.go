@@ -0,0 +1,39 @@
+package tar
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// addDirect writes a regular file entry straight to the target file,
+// bypassing the tar writer for its content, so it can be copied in the
+// kernel, e.g. with copy_file_range or sendfile on Linux, without going
+// through user space.
+//
+// The standard library tar writer hides the io.ReaderFrom implementation of
+// its underlying writer, so the header is created by a separate tar writer,
+// like sparse entries are.
+func (a Archive) addDirect(target *os.File, header *tar.Header, file *os.File) error {
+	var buf bytes.Buffer
+	if err := tar.NewWriter(&buf).WriteHeader(header); err != nil {
+		return fmt.Errorf("%s: %w", header.Name, err)
+	}
+	if err := a.tw.Flush(); err != nil {
+		return fmt.Errorf("%s: %w", header.Name, err)
+	}
+	if _, err := target.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("%s: %w", header.Name, err)
+	}
+	if _, err := io.CopyN(target, file, header.Size); err != nil {
+		return fmt.Errorf("%s: %w", file.Name(), err)
+	}
+	if pad := header.Size % blockSize; pad != 0 {
+		if _, err := target.Write(make([]byte, blockSize-pad)); err != nil {
+			return fmt.Errorf("%s: %w", header.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package tar
+
+import (
+	"archive/tar"
+	"fmt"
+	"os/user"
+	"strconv"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// resolveOwners sets the numeric uid and gid of the given header from the
+// owner and group names in the given file info, by looking them up in the
+// system, e.g. in /etc/passwd and /etc/group.
+func resolveOwners(header *tar.Header, info config.FileInfo) error {
+	if info.Owner != "" {
+		u, err := user.Lookup(info.Owner)
+		if err != nil {
+			return fmt.Errorf("%s: could not resolve owner: %w", header.Name, err)
+		}
+		uid, err := strconv.Atoi(u.Uid)
+		if err != nil {
+			return fmt.Errorf("%s: owner %s has a non-numeric uid: %s", header.Name, info.Owner, u.Uid)
+		}
+		header.Uid = uid
+	}
+	if info.Group != "" {
+		g, err := user.LookupGroup(info.Group)
+		if err != nil {
+			return fmt.Errorf("%s: could not resolve group: %w", header.Name, err)
+		}
+		gid, err := strconv.Atoi(g.Gid)
+		if err != nil {
+			return fmt.Errorf("%s: group %s has a non-numeric gid: %s", header.Name, info.Group, g.Gid)
+		}
+		header.Gid = gid
+	}
+	return nil
+}
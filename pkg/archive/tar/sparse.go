@@ -0,0 +1,157 @@
+package tar
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"time"
+	"unicode"
+)
+
+// region is a region of a sparse file which contains data.
+type region struct {
+	offset, length int64
+}
+
+// sparseHeader creates the raw headers of a sparse entry, using the PAX
+// GNU sparse format 1.0, along with its sparse map, which is stored at the
+// beginning of the entry data, and is followed by the data regions.
+//
+// The standard library tar writer does not allow to write the GNU.sparse PAX
+// records, so the PAX extended header is written as a regular file entry, and
+// then has its type flag patched.
+func sparseHeader(header *tar.Header, regions []region) ([]byte, error) {
+	var sparseMap bytes.Buffer
+	fmt.Fprintf(&sparseMap, "%d\n", len(regions))
+	var size int64
+	for _, r := range regions {
+		fmt.Fprintf(&sparseMap, "%d\n%d\n", r.offset, r.length)
+		size += r.length
+	}
+	if pad := sparseMap.Len() % blockSize; pad != 0 {
+		sparseMap.Write(make([]byte, blockSize-pad))
+	}
+
+	var records bytes.Buffer
+	for _, kv := range [][2]string{
+		{"GNU.sparse.major", "1"},
+		{"GNU.sparse.minor", "0"},
+		{"GNU.sparse.name", header.Name},
+		{"GNU.sparse.realsize", strconv.FormatInt(header.Size, 10)},
+	} {
+		records.WriteString(paxRecord(kv[0], kv[1]))
+	}
+	// other records, like extended attributes, are kept.
+	for _, k := range slices.Sorted(maps.Keys(header.PAXRecords)) {
+		records.WriteString(paxRecord(k, header.PAXRecords[k]))
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     "PaxHeaders.0/GNUSparseFile",
+		Mode:     0o644,
+		Size:     int64(records.Len()),
+		ModTime:  header.ModTime.Truncate(time.Second),
+		Format:   tar.FormatUSTAR,
+	}); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(records.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := tw.Flush(); err != nil {
+		return nil, err
+	}
+	setTypeflag(buf.Bytes()[:blockSize], tar.TypeXHeader)
+
+	main := *header
+	main.Name = "GNUSparseFile.0/" + sparseBase(header.Name)
+	main.Size = int64(sparseMap.Len()) + size
+	main.ModTime = header.ModTime.Truncate(time.Second)
+	main.AccessTime = time.Time{}
+	main.ChangeTime = time.Time{}
+	main.PAXRecords = nil
+	main.Format = tar.FormatUSTAR
+	if err := tw.WriteHeader(&main); err != nil {
+		return nil, err
+	}
+	buf.Write(sparseMap.Bytes())
+	return buf.Bytes(), nil
+}
+
+// addSparse writes a sparse entry, with the given raw header, created by
+// [sparseHeader], followed by the data regions of the given file.
+func (a Archive) addSparse(raw []byte, file *os.File, regions []region) error {
+	if err := a.tw.Flush(); err != nil {
+		return fmt.Errorf("%s: %w", file.Name(), err)
+	}
+	if _, err := a.w.Write(raw); err != nil {
+		return fmt.Errorf("%s: %w", file.Name(), err)
+	}
+	var size int64
+	for _, r := range regions {
+		if _, err := file.Seek(r.offset, io.SeekStart); err != nil {
+			return fmt.Errorf("%s: %w", file.Name(), err)
+		}
+		if _, err := io.CopyN(a.w, file, r.length); err != nil {
+			return fmt.Errorf("%s: %w", file.Name(), err)
+		}
+		size += r.length
+	}
+	if pad := size % blockSize; pad != 0 {
+		if _, err := a.w.Write(make([]byte, blockSize-pad)); err != nil {
+			return fmt.Errorf("%s: %w", file.Name(), err)
+		}
+	}
+	return nil
+}
+
+// paxRecord formats a PAX record, which is prefixed by its own length.
+func paxRecord(k, v string) string {
+	const padding = 3 // extra padding for ' ', '=', and '\n'
+	size := len(k) + len(v) + padding
+	size += len(strconv.Itoa(size))
+	record := strconv.Itoa(size) + " " + k + "=" + v + "\n"
+	// the length might have grown by a digit.
+	if len(record) != size {
+		size = len(record)
+		record = strconv.Itoa(size) + " " + k + "=" + v + "\n"
+	}
+	return record
+}
+
+// setTypeflag changes the type flag of the given raw header block, updating
+// its checksum accordingly.
+func setTypeflag(blk []byte, flag byte) {
+	blk[156] = flag
+	chksum := blk[148:156]
+	copy(chksum, "        ")
+	var sum int64
+	for _, c := range blk {
+		sum += int64(c)
+	}
+	copy(chksum, fmt.Sprintf("%06o\x00", sum))
+}
+
+// sparseBase returns the base name used by the header of sparse entries,
+// which must fit in a USTAR header. The real name is in the PAX records.
+func sparseBase(name string) string {
+	base := path.Base(name)
+	if len(base) > 80 {
+		return "file"
+	}
+	for _, r := range base {
+		if r > unicode.MaxASCII {
+			return "file"
+		}
+	}
+	return base
+}
@@ -0,0 +1,47 @@
+package tar
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// whence values for lseek(2) to find data and holes in a file.
+const (
+	seekData = 3
+	seekHole = 4
+)
+
+// dataRegions returns the regions of the given file which hold data, or nil
+// if the file has no holes, or the file system can't tell where they are.
+//
+// The file offset is left at an unspecified position.
+func dataRegions(f *os.File, size int64) []region {
+	var regions []region
+	var off int64
+	for off < size {
+		data, err := f.Seek(off, seekData)
+		if errors.Is(err, syscall.ENXIO) {
+			// no more data until the end of the file.
+			break
+		}
+		if err != nil {
+			// file system does not support it.
+			return nil
+		}
+		hole, err := f.Seek(data, seekHole)
+		if err != nil {
+			return nil
+		}
+		regions = append(regions, region{offset: data, length: hole - data})
+		off = hole
+	}
+	if len(regions) == 1 && regions[0].offset == 0 && regions[0].length == size {
+		return nil
+	}
+	if last := len(regions) - 1; last < 0 || regions[last].offset+regions[last].length < size {
+		// the sparse map must always end at the end of the file.
+		regions = append(regions, region{offset: size})
+	}
+	return regions
+}
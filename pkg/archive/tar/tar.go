@@ -3,105 +3,517 @@ package tar
 
 import (
 	"archive/tar"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/closed"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/copybuf"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/destination"
 	"github.com/goreleaser/goreleaser/v2/pkg/config"
 )
 
 // Archive as tar.
+//
+// Names and link names which don't fit in an USTAR header, like the ones
+// longer than 100 bytes, are written using PAX extended headers.
+//
+// When writing directly to an [os.File], the content of regular files is
+// copied in the kernel where possible.
 type Archive struct {
-	tw    *tar.Writer
-	files map[string]bool
+	w      io.Writer
+	tw     *tar.Writer
+	files  map[string]bool
+	folded destination.Folded
+	sparse bool
+	xattrs bool
+	closed *closed.Flag
+
+	bufSize       int
+	links         linkPolicy
+	resolveOwners bool
+	record        *recordWriter
+}
+
+// linkPolicy is what to do with symlinks whose target escapes the archive
+// root.
+type linkPolicy int
+
+const (
+	keepLinks linkPolicy = iota
+	rejectUnsafeLinks
+	rewriteUnsafeLinks
+)
+
+// Option customizes the tar archive.
+type Option func(*Archive)
+
+// WithCaseInsensitiveCheck makes Add fail when the destination only differs
+// by case from a previously added one, as they would overwrite each other
+// when extracted in case-insensitive file systems (e.g. macOS and Windows).
+func WithCaseInsensitiveCheck() Option {
+	return func(a *Archive) {
+		a.folded = destination.Folded{}
+	}
+}
+
+// WithSparseFiles makes files with holes be written as PAX sparse entries
+// (GNU sparse format 1.0), instead of having their holes written as zeroes.
+//
+// Holes are only detected on Linux, in other platforms, and in file systems
+// that do not support it, files are always written in full.
+func WithSparseFiles() Option {
+	return func(a *Archive) {
+		a.sparse = true
+	}
+}
+
+// WithXattrs makes the extended attributes of the added files, such as file
+// capabilities and SELinux contexts, be written as PAX records
+// (SCHILY.xattr.*).
+//
+// Extended attributes are only read on Linux, in other platforms this does
+// nothing.
+func WithXattrs() Option {
+	return func(a *Archive) {
+		a.xattrs = true
+	}
+}
+
+// WithCopyBufferSize sets the size of the buffer used to copy the content of
+// files into the archive, which can speed up adding large files.
+// Zero keeps the default size, 32KB.
+func WithCopyBufferSize(size int) Option {
+	return func(a *Archive) {
+		a.bufSize = size
+	}
+}
+
+// WithRejectUnsafeSymlinks makes Add fail when adding a symlink whose target
+// is absolute or, once resolved, escapes the archive root, as extracting it
+// could make later entries be written outside of the extraction directory.
+func WithRejectUnsafeSymlinks() Option {
+	return func(a *Archive) {
+		a.links = rejectUnsafeLinks
+	}
+}
+
+// WithRewriteUnsafeSymlinks makes the targets of symlinks which are absolute
+// or, once resolved, escape the archive root, be rewritten to relative ones
+// inside it, as if the archive root was the file system root.
+// E.g. a bin/sh symlink to /usr/bin/bash is written as a link to
+// ../usr/bin/bash.
+func WithRewriteUnsafeSymlinks() Option {
+	return func(a *Archive) {
+		a.links = rewriteUnsafeLinks
+	}
+}
+
+// WithResolveOwnerNames makes the owner and group names in the file info be
+// resolved to their numeric uid and gid, by looking them up in the system,
+// instead of writing them with uid and gid 0.
+// Add fails if a name can't be resolved.
+func WithResolveOwnerNames() Option {
+	return func(a *Archive) {
+		a.resolveOwners = true
+	}
+}
+
+// WithRecordSize makes the whole archive be padded with zeroes, when closed,
+// to a multiple of the given record size, e.g. 10240 bytes, which is the
+// default blocking factor of 20 of GNU tar, as required by some tape drives
+// and streaming consumers.
+// The size must be a multiple of 512 bytes, otherwise Close fails.
+//
+// Files are not copied in the kernel when a record size is set.
+func WithRecordSize(size int) Option {
+	return func(a *Archive) {
+		a.record = &recordWriter{size: int64(size)}
+	}
 }
 
 // New tar archive.
-func New(target io.Writer) Archive {
-	return Archive{
-		tw:    tar.NewWriter(target),
-		files: map[string]bool{},
+func New(target io.Writer, opts ...Option) Archive {
+	a := Archive{
+		w:      target,
+		files:  map[string]bool{},
+		closed: &closed.Flag{},
+	}
+	for _, opt := range opts {
+		opt(&a)
 	}
+	if a.record != nil {
+		a.record.w = target
+		a.w = a.record
+	}
+	a.tw = tar.NewWriter(a.w)
+	return a
 }
 
 // Copy creates a new tar with the contents of the given tar.
-func Copy(source io.Reader, target io.Writer) (Archive, error) {
-	w := New(target)
+//
+// Entries are copied as they are, in the same order, preserving all their
+// header fields, so only the files added afterwards differ from the source.
+// They are streamed from the source, so memory usage does not grow with the
+// size of the archive.
+func Copy(source io.Reader, target io.Writer, opts ...Option) (Archive, error) {
+	w := New(target, opts...)
 	r := tar.NewReader(source)
 	for {
 		header, err := r.Next()
-		if err == io.EOF || header == nil {
+		if errors.Is(err, io.EOF) {
 			break
 		}
 		if err != nil {
 			return Archive{}, err
 		}
-		w.files[header.Name] = true
+		name := strings.TrimSuffix(header.Name, "/")
+		w.files[name] = true
+		if w.folded != nil {
+			w.folded[strings.ToLower(name)] = name
+		}
 		if err := w.tw.WriteHeader(header); err != nil {
 			return w, err
 		}
-		if _, err := io.Copy(w.tw, r); err != nil {
+		if _, err := copybuf.Copy(w.tw, r, w.bufSize); err != nil {
 			return w, err
 		}
 	}
 	return w, nil
 }
 
+// Format returns the archive format, "tar".
+func (a Archive) Format() string {
+	return "tar"
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (a Archive) Flush() error {
+	return a.tw.Flush()
+}
+
 // Close all closeables.
 func (a Archive) Close() error {
-	return a.tw.Close()
+	if err := a.closed.Close(); err != nil {
+		return err
+	}
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	if a.record != nil {
+		return a.record.pad()
+	}
+	return nil
 }
 
 // Add file to the archive.
 func (a Archive) Add(f config.File) error {
-	if _, ok := a.files[f.Destination]; ok {
-		return &fs.PathError{Err: fs.ErrExist, Path: f.Destination, Op: "add"}
+	// entry names always use forward slashes, even when built on Windows.
+	f.Destination = filepath.ToSlash(f.Destination)
+	if err := a.closed.Check(f.Destination); err != nil {
+		return err
 	}
-	a.files[f.Destination] = true
-	info, err := os.Lstat(f.Source) // #nosec
+	if err := a.register(f.Destination); err != nil {
+		return err
+	}
+	header, err := fileHeader(f)
+	if err != nil {
+		return err
+	}
+	if a.resolveOwners {
+		if err := resolveOwners(header, f.Info); err != nil {
+			return err
+		}
+	}
+	if header.Typeflag == tar.TypeSymlink {
+		if err := a.checkLink(header); err != nil {
+			return err
+		}
+	}
+	if a.xattrs && f.Source != "" && (header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeDir) {
+		attrs, err := xattrs(f.Source)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Source, err)
+		}
+		for k, v := range attrs {
+			if header.PAXRecords == nil {
+				header.PAXRecords = map[string]string{}
+			}
+			header.PAXRecords["SCHILY.xattr."+k] = v
+		}
+	}
+	if header.Typeflag != tar.TypeReg {
+		if err = a.tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("%s: %w", header.Name, err)
+		}
+		return nil
+	}
+	file, err := os.Open(f.Source) // #nosec
 	if err != nil {
 		return fmt.Errorf("%s: %w", f.Source, err)
 	}
+	defer file.Close()
+	if a.sparse && header.Size > 0 {
+		if regions := dataRegions(file, header.Size); regions != nil {
+			// fall back to a regular entry if the sparse one can't be created.
+			if raw, err := sparseHeader(header, regions); err == nil {
+				return a.addSparse(raw, file, regions)
+			}
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("%s: %w", f.Source, err)
+		}
+	}
+	if target, ok := a.w.(*os.File); ok {
+		return a.addDirect(target, header, file)
+	}
+	if err = a.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("%s: %w", header.Name, err)
+	}
+	if _, err := copybuf.Copy(a.tw, file, a.bufSize); err != nil {
+		return fmt.Errorf("%s: %w", f.Source, err)
+	}
+	return nil
+}
+
+// AddWithReader adds a regular file to the archive, reading its content from
+// r, with the size, mode and modification time of the given info, unless set
+// in the file info.
+func (a Archive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	// entry names always use forward slashes, even when built on Windows.
+	f.Destination = filepath.ToSlash(f.Destination)
+	if err := a.closed.Check(f.Destination); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s: not a regular file: %s", f.Destination, info.Mode().Type())
+	}
+	if err := a.register(f.Destination); err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Destination, err)
+	}
+	header.Name = f.Destination
+	applyInfo(header, f.Info)
+	if a.resolveOwners {
+		if err := resolveOwners(header, f.Info); err != nil {
+			return err
+		}
+	}
+	if err := a.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("%s: %w", header.Name, err)
+	}
+	if _, err := copybuf.Copy(a.tw, r, a.bufSize); err != nil {
+		return fmt.Errorf("%s: %w", header.Name, err)
+	}
+	return nil
+}
+
+// checkLink applies the link policy of the archive to the given symlink.
+func (a Archive) checkLink(header *tar.Header) error {
+	switch a.links {
+	case rejectUnsafeLinks:
+		return destination.ValidateLink(header.Name, header.Linkname)
+	case rewriteUnsafeLinks:
+		if destination.ValidateLink(header.Name, header.Linkname) != nil {
+			header.Linkname = destination.SafeLink(header.Name, header.Linkname)
+		}
+	}
+	return nil
+}
+
+// register validates the given destination, and marks it as added.
+func (a Archive) register(dst string) error {
+	if err := destination.Validate(dst); err != nil {
+		return err
+	}
+	// directories might be added with or without a trailing slash.
+	dst = strings.TrimSuffix(dst, "/")
+	if _, ok := a.files[dst]; ok {
+		return &fs.PathError{Err: fs.ErrExist, Path: dst, Op: "add"}
+	}
+	if a.folded != nil {
+		if err := a.folded.Add(dst); err != nil {
+			return err
+		}
+	}
+	a.files[dst] = true
+	return nil
+}
+
+// EstimateSize returns the size of an uncompressed tar archive containing
+// the given files.
+//
+// Files are not read, only stat'ed, so the result might be off if they change
+// in between, or if they are larger than 8GiB.
+func EstimateSize(files []config.File) (int64, error) {
+	var size int64
+	for _, f := range files {
+		header, err := fileHeader(f)
+		if err != nil {
+			return 0, err
+		}
+		content := header.Size
+		header.Size = 0
+		var c counter
+		tw := tar.NewWriter(&c)
+		if err := tw.WriteHeader(header); err != nil {
+			return 0, fmt.Errorf("%s: %w", header.Name, err)
+		}
+		if err := tw.Flush(); err != nil {
+			return 0, fmt.Errorf("%s: %w", header.Name, err)
+		}
+		size += c.n + (content+blockSize-1)/blockSize*blockSize
+	}
+	// a tar archive ends with two zero blocks.
+	return size + 2*blockSize, nil
+}
+
+const blockSize = 512
+
+// recordWriter counts what is written to the archive, so it can be padded to
+// a multiple of its record size.
+type recordWriter struct {
+	w    io.Writer
+	n    int64
+	size int64
+}
+
+func (r *recordWriter) Write(p []byte) (int, error) {
+	n, err := r.w.Write(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// pad writes the zeroes needed to fill the last record.
+func (r *recordWriter) pad() error {
+	if r.size <= 0 || r.size%blockSize != 0 {
+		return fmt.Errorf("invalid record size %d: must be a multiple of %d", r.size, blockSize)
+	}
+	if rem := r.n % r.size; rem != 0 {
+		_, err := r.Write(make([]byte, r.size-rem))
+		return err
+	}
+	return nil
+}
+
+type counter struct{ n int64 }
+
+func (c *counter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// fileHeader creates the tar header for the given file.
+func fileHeader(f config.File) (*tar.Header, error) {
+	if f.Source == "" && f.Info.Mode.IsDir() {
+		return dirHeader(f), nil
+	}
+	if f.Source == "" && f.Info.Mode&(fs.ModeDevice|fs.ModeNamedPipe) != 0 {
+		return deviceHeader(f), nil
+	}
+	info, err := os.Lstat(f.Source) // #nosec
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Source, err)
+	}
 	var link string
 	if info.Mode()&os.ModeSymlink != 0 {
 		link, err = os.Readlink(f.Source) // #nosec
 		if err != nil {
-			return fmt.Errorf("%s: %w", f.Source, err)
+			return nil, fmt.Errorf("%s: %w", f.Source, err)
 		}
 	}
 	header, err := tar.FileInfoHeader(info, link)
 	if err != nil {
-		return fmt.Errorf("%s: %w", f.Source, err)
+		return nil, fmt.Errorf("%s: %w", f.Source, err)
 	}
 	header.Name = f.Destination
-	if !f.Info.ParsedMTime.IsZero() {
-		header.ModTime = f.Info.ParsedMTime
+	applyInfo(header, f.Info)
+	return header, nil
+}
+
+// applyInfo overrides the fields of the given header set in the file info.
+func applyInfo(header *tar.Header, info config.FileInfo) {
+	if !info.ParsedMTime.IsZero() {
+		header.ModTime = info.ParsedMTime
 	}
-	if f.Info.Mode != 0 {
-		header.Mode = int64(f.Info.Mode)
+	if info.Mode != 0 {
+		header.Mode = headerMode(info.Mode)
 	}
-	if f.Info.Owner != "" {
+	if info.Owner != "" {
 		header.Uid = 0
-		header.Uname = f.Info.Owner
+		header.Uname = info.Owner
 	}
-	if f.Info.Group != "" {
+	if info.Group != "" {
 		header.Gid = 0
-		header.Gname = f.Info.Group
+		header.Gname = info.Group
 	}
-	if err = a.tw.WriteHeader(header); err != nil {
-		return fmt.Errorf("%s: %w", f.Source, err)
+}
+
+// headerMode returns the tar mode of the given file mode, converting its
+// setuid, setgid and sticky bits, and keeping the raw unix ones, e.g. from a
+// 0o4755 mode.
+func headerMode(mode fs.FileMode) int64 {
+	m := int64(mode & (fs.ModePerm | 0o7000))
+	if mode&fs.ModeSetuid != 0 {
+		m |= 0o4000
 	}
-	if info.IsDir() || info.Mode()&os.ModeSymlink != 0 {
-		return nil
+	if mode&fs.ModeSetgid != 0 {
+		m |= 0o2000
 	}
-	file, err := os.Open(f.Source) // #nosec
-	if err != nil {
-		return fmt.Errorf("%s: %w", f.Source, err)
+	if mode&fs.ModeSticky != 0 {
+		m |= 0o1000
 	}
-	defer file.Close()
-	if _, err := io.Copy(a.tw, file); err != nil {
-		return fmt.Errorf("%s: %w", f.Source, err)
+	return m
+}
+
+// dirHeader creates the header of an explicit directory entry, which has no
+// source in the disk.
+func dirHeader(f config.File) *tar.Header {
+	header := &tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     strings.TrimSuffix(f.Destination, "/") + "/",
+		Mode:     headerMode(f.Info.Mode),
+		ModTime:  f.Info.ParsedMTime,
+		Uname:    f.Info.Owner,
+		Gname:    f.Info.Group,
 	}
-	return nil
+	if header.ModTime.IsZero() {
+		header.ModTime = time.Now()
+	}
+	return header
+}
+
+// deviceHeader creates the header of a device or named pipe entry, declared
+// by its mode, which has no source in the disk.
+func deviceHeader(f config.File) *tar.Header {
+	header := &tar.Header{
+		Typeflag: tar.TypeFifo,
+		Name:     f.Destination,
+		Mode:     headerMode(f.Info.Mode),
+		ModTime:  f.Info.ParsedMTime,
+		Uname:    f.Info.Owner,
+		Gname:    f.Info.Group,
+	}
+	switch {
+	case f.Info.Mode&fs.ModeCharDevice != 0:
+		header.Typeflag = tar.TypeChar
+	case f.Info.Mode&fs.ModeDevice != 0:
+		header.Typeflag = tar.TypeBlock
+	}
+	if header.Typeflag != tar.TypeFifo {
+		header.Devmajor = f.Info.DevMajor
+		header.Devminor = f.Info.DevMinor
+	}
+	if header.ModTime.IsZero() {
+		header.ModTime = time.Now()
+	}
+	return header
 }
@@ -2,14 +2,24 @@ package tar
 
 import (
 	"archive/tar"
+	"bytes"
+	"errors"
 	"io"
 	"io/fs"
+	"math/rand/v2"
 	"os"
+	"os/user"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
+	"unicode/utf8"
 
 	"github.com/goreleaser/goreleaser/v2/internal/testlib"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/destination"
 	"github.com/goreleaser/goreleaser/v2/pkg/config"
 	"github.com/stretchr/testify/require"
 )
@@ -212,3 +222,679 @@ func TestCopying(t *testing.T) {
 	require.Equal(t, []string{"foo.txt", "ملف.txt"}, testlib.LsArchive(t, f1.Name(), "tar"))
 	require.Equal(t, []string{"foo.txt", "ملف.txt", "executable", "ملف.exe"}, testlib.LsArchive(t, f2.Name(), "tar"))
 }
+
+func TestTarEmptyDir(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.tar"))
+	require.NoError(t, err)
+	defer f.Close()
+	archive := New(f)
+	defer archive.Close()
+
+	require.NoError(t, archive.Add(config.File{
+		Destination: "logs/",
+		Info: config.FileInfo{
+			Mode:        os.ModeDir | 0o750,
+			Owner:       "carlos",
+			ParsedMTime: now,
+		},
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Destination: "plugins",
+		Info: config.FileInfo{
+			Mode: os.ModeDir | 0o755,
+		},
+	}))
+	require.ErrorIs(t, archive.Add(config.File{
+		Destination: "logs",
+		Info: config.FileInfo{
+			Mode: os.ModeDir | 0o750,
+		},
+	}), fs.ErrExist)
+	require.Error(t, archive.Add(config.File{
+		Destination: "not-a-dir",
+	}))
+	require.NoError(t, archive.Close())
+	require.NoError(t, f.Close())
+
+	f, err = os.Open(f.Name())
+	require.NoError(t, err)
+	defer f.Close()
+
+	var headers []*tar.Header
+	r := tar.NewReader(f)
+	for {
+		next, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		headers = append(headers, next)
+	}
+	require.Len(t, headers, 2)
+	require.Equal(t, "logs/", headers[0].Name)
+	require.Equal(t, byte(tar.TypeDir), headers[0].Typeflag)
+	require.Equal(t, os.ModeDir|0o750, headers[0].FileInfo().Mode())
+	require.Equal(t, now, headers[0].ModTime)
+	require.Equal(t, "carlos", headers[0].Uname)
+	require.Equal(t, "plugins/", headers[1].Name)
+	require.Equal(t, byte(tar.TypeDir), headers[1].Typeflag)
+	require.Equal(t, os.ModeDir|0o755, headers[1].FileInfo().Mode())
+}
+
+func TestCopyingCaseInsensitiveCheck(t *testing.T) {
+	f1, err := os.Create(filepath.Join(t.TempDir(), "1.tar"))
+	require.NoError(t, err)
+	t1 := New(f1)
+	require.NoError(t, t1.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "README",
+	}))
+	require.NoError(t, t1.Close())
+	require.NoError(t, f1.Close())
+
+	f1, err = os.Open(f1.Name())
+	require.NoError(t, err)
+	defer f1.Close()
+
+	t2, err := Copy(f1, io.Discard, WithCaseInsensitiveCheck())
+	require.NoError(t, err)
+	defer t2.Close()
+	require.ErrorIs(t, t2.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "Readme",
+	}), fs.ErrExist)
+}
+
+func TestTarAddWithReader(t *testing.T) {
+	var buf bytes.Buffer
+	archive := New(&buf)
+	require.NoError(t, addMapFile(t, archive, "app/bin/app", &fstest.MapFile{Data: []byte("app"), Mode: 0o755}))
+	require.NoError(t, addMapFile(t, archive, "app/etc/app.yml", &fstest.MapFile{Data: []byte("a: b"), Mode: 0o640}))
+	require.NoError(t, archive.AddWithReader(config.File{
+		Destination: "app/etc/override.yml",
+		Info:        config.FileInfo{Mode: 0o600, Owner: "root"},
+	}, mapFileInfo(t, &fstest.MapFile{Mode: 0o644}), strings.NewReader("")))
+	require.ErrorIs(t, addMapFile(t, archive, "app/bin/app", &fstest.MapFile{}), fs.ErrExist)
+	require.Error(t, archive.AddWithReader(config.File{Destination: "dir"}, mapFileInfo(t, &fstest.MapFile{Mode: fs.ModeDir}), strings.NewReader("")))
+	require.NoError(t, archive.Close())
+
+	modes := map[string]fs.FileMode{}
+	r := tar.NewReader(&buf)
+	for {
+		next, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		modes[next.Name] = next.FileInfo().Mode()
+		if next.Name == "app/etc/override.yml" {
+			require.Equal(t, "root", next.Uname)
+		}
+	}
+	require.Equal(t, map[string]fs.FileMode{
+		"app/bin/app":          0o755,
+		"app/etc/app.yml":      0o640,
+		"app/etc/override.yml": 0o600,
+	}, modes)
+}
+
+// addMapFile adds the given file at the given destination with
+// AddWithReader.
+func addMapFile(tb testing.TB, a Archive, dst string, file *fstest.MapFile) error {
+	tb.Helper()
+	return a.AddWithReader(config.File{Destination: dst}, mapFileInfo(tb, file), bytes.NewReader(file.Data))
+}
+
+func mapFileInfo(tb testing.TB, file *fstest.MapFile) fs.FileInfo {
+	tb.Helper()
+	info, err := fs.Stat(fstest.MapFS{"file": file}, "file")
+	require.NoError(tb, err)
+	return info
+}
+
+func TestTarSparseFile(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("holes are only detected on linux")
+	}
+
+	const size = 64 * 1024 * 1024
+	sparse, err := os.Create(filepath.Join(t.TempDir(), "sparse.img"))
+	require.NoError(t, err)
+	require.NoError(t, sparse.Truncate(size))
+	_, err = sparse.WriteAt([]byte("begin"), 0)
+	require.NoError(t, err)
+	_, err = sparse.WriteAt([]byte("middle"), size/2)
+	require.NoError(t, err)
+	require.NoError(t, sparse.Close())
+	if dataRegions(openFile(t, sparse.Name()), size) == nil {
+		t.Skip("file system does not support holes")
+	}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.tar"))
+	require.NoError(t, err)
+	defer f.Close()
+	archive := New(f, WithSparseFiles())
+	require.NoError(t, archive.Add(config.File{
+		Source:      sparse.Name(),
+		Destination: "disk/sparse.img",
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	require.NoError(t, archive.Close())
+	require.NoError(t, f.Close())
+
+	info, err := os.Stat(f.Name())
+	require.NoError(t, err)
+	require.Less(t, info.Size(), int64(size/100))
+
+	r := tar.NewReader(openFile(t, f.Name()))
+	next, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, "disk/sparse.img", next.Name)
+	require.Equal(t, int64(size), next.Size)
+	bts, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Len(t, bts, size)
+	require.Equal(t, "begin", string(bts[:5]))
+	require.Equal(t, "middle", string(bts[size/2:size/2+6]))
+	require.Equal(t, make([]byte, 1024), bts[size-1024:])
+
+	next, err = r.Next()
+	require.NoError(t, err)
+	require.Equal(t, "foo.txt", next.Name)
+	_, err = r.Next()
+	require.Equal(t, io.EOF, err)
+}
+
+func openFile(tb testing.TB, path string) *os.File {
+	tb.Helper()
+	f, err := os.Open(path)
+	require.NoError(tb, err)
+	tb.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestTarLongPaths(t *testing.T) {
+	long := strings.Repeat("ملف/", 49) + "ملف.txt"
+	require.GreaterOrEqual(t, utf8.RuneCountInString(long), 200)
+	ascii := strings.Repeat("a", 100) + "/" + strings.Repeat("b", 99) + ".txt"
+	require.Len(t, ascii, 204)
+
+	linkname := strings.Repeat("c/", 75) + "target.txt"
+	link := filepath.Join(t.TempDir(), "link")
+	require.NoError(t, os.Symlink(linkname, link))
+
+	var buf bytes.Buffer
+	archive := New(&buf)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: long,
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      link,
+		Destination: ascii,
+	}))
+	require.NoError(t, addMapFile(t, archive, long+"/foo.txt", &fstest.MapFile{Data: []byte("foo"), Mode: 0o644}))
+	require.NoError(t, archive.Close())
+
+	r := tar.NewReader(&buf)
+	next, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, long, next.Name)
+	bts, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "foo\n", string(bts))
+
+	next, err = r.Next()
+	require.NoError(t, err)
+	require.Equal(t, ascii, next.Name)
+	require.Equal(t, linkname, next.Linkname)
+	require.Equal(t, byte(tar.TypeSymlink), next.Typeflag)
+
+	next, err = r.Next()
+	require.NoError(t, err)
+	require.Equal(t, long+"/foo.txt", next.Name)
+
+	_, err = r.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestCopyingPreservesHeaders(t *testing.T) {
+	mtime := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	headers := []*tar.Header{
+		{
+			Typeflag: tar.TypeDir,
+			Name:     "bin/",
+			Mode:     0o755,
+			Uid:      1000,
+			Gid:      1000,
+			Uname:    "carlos",
+			Gname:    "staff",
+			ModTime:  mtime,
+		},
+		{
+			Typeflag: tar.TypeReg,
+			Name:     "bin/setuid",
+			Mode:     0o4755,
+			Uid:      0,
+			Gid:      0,
+			Uname:    "root",
+			Gname:    "root",
+			ModTime:  mtime,
+			Size:     4,
+		},
+		{
+			Typeflag: tar.TypeSymlink,
+			Name:     "bin/link",
+			Linkname: "setuid",
+			Mode:     0o777,
+			Uid:      1000,
+			Gid:      1000,
+			ModTime:  mtime,
+		},
+		{
+			Typeflag: tar.TypeLink,
+			Name:     "bin/hardlink",
+			Linkname: "bin/setuid",
+			Mode:     0o2750,
+			ModTime:  mtime,
+		},
+		{
+			Typeflag:   tar.TypeReg,
+			Name:       "long/" + strings.Repeat("a", 150),
+			Mode:       0o600,
+			ModTime:    mtime.Add(time.Millisecond),
+			Size:       4,
+			PAXRecords: map[string]string{"SCHILY.xattr.user.foo": "bar"},
+			Format:     tar.FormatPAX,
+		},
+	}
+
+	var src bytes.Buffer
+	tw := tar.NewWriter(&src)
+	for _, h := range headers {
+		require.NoError(t, tw.WriteHeader(h))
+		if h.Size > 0 {
+			_, err := tw.Write([]byte("data"))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+
+	read := func(tb testing.TB, bts []byte) []*tar.Header {
+		tb.Helper()
+		var result []*tar.Header
+		r := tar.NewReader(bytes.NewReader(bts))
+		for {
+			next, err := r.Next()
+			if errors.Is(err, io.EOF) {
+				return result
+			}
+			require.NoError(tb, err)
+			result = append(result, next)
+		}
+	}
+
+	var dst bytes.Buffer
+	archive, err := Copy(bytes.NewReader(src.Bytes()), &dst)
+	require.NoError(t, err)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	require.NoError(t, archive.Close())
+
+	want := read(t, src.Bytes())
+	got := read(t, dst.Bytes())
+	require.Len(t, got, len(want)+1)
+	require.Equal(t, want, got[:len(want)])
+	require.Equal(t, "foo.txt", got[len(want)].Name)
+}
+
+func TestCopyingTruncated(t *testing.T) {
+	var src bytes.Buffer
+	archive := New(&src)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	require.NoError(t, archive.Close())
+
+	_, err := Copy(bytes.NewReader(src.Bytes()[:514]), io.Discard)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestTarCopyBufferSize(t *testing.T) {
+	content := make([]byte, 100_000)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(content)
+	src := filepath.Join(t.TempDir(), "random.bin")
+	require.NoError(t, os.WriteFile(src, content, 0o644))
+
+	for _, size := range []int{7, 1 << 20} {
+		var buf bytes.Buffer
+		archive := New(&buf, WithCopyBufferSize(size))
+		require.NoError(t, archive.Add(config.File{
+			Source:      src,
+			Destination: "random.bin",
+		}))
+		require.NoError(t, addMapFile(t, archive, "fs/random.bin", &fstest.MapFile{Data: content, Mode: 0o644}))
+		require.NoError(t, archive.Close())
+
+		r := tar.NewReader(&buf)
+		for _, name := range []string{"random.bin", "fs/random.bin"} {
+			header, err := r.Next()
+			require.NoError(t, err)
+			require.Equal(t, name, header.Name)
+			bts, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, content, bts)
+		}
+	}
+}
+
+func TestTarUnsafeSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	link := func(tb testing.TB, name, target string) string {
+		tb.Helper()
+		p := filepath.Join(dir, name)
+		require.NoError(tb, os.Symlink(target, p))
+		return p
+	}
+	safe := link(t, "safe", "../lib/libfoo.so")
+	escaping := link(t, "escaping", "../../etc/passwd")
+	absolute := link(t, "absolute", "/usr/bin/bash")
+
+	links := func(tb testing.TB, buf *bytes.Buffer) map[string]string {
+		tb.Helper()
+		result := map[string]string{}
+		r := tar.NewReader(buf)
+		for {
+			next, err := r.Next()
+			if errors.Is(err, io.EOF) {
+				return result
+			}
+			require.NoError(tb, err)
+			require.Equal(tb, byte(tar.TypeSymlink), next.Typeflag)
+			result[next.Name] = next.Linkname
+		}
+	}
+
+	t.Run("default", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive := New(&buf)
+		require.NoError(t, archive.Add(config.File{Source: escaping, Destination: "bin/escaping"}))
+		require.NoError(t, archive.Add(config.File{Source: absolute, Destination: "bin/sh"}))
+		require.NoError(t, archive.Close())
+		require.Equal(t, map[string]string{
+			"bin/escaping": "../../etc/passwd",
+			"bin/sh":       "/usr/bin/bash",
+		}, links(t, &buf))
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive := New(&buf, WithRejectUnsafeSymlinks())
+		require.NoError(t, archive.Add(config.File{Source: safe, Destination: "bin/libfoo.so"}))
+		require.ErrorIs(t, archive.Add(config.File{Source: escaping, Destination: "bin/escaping"}), destination.ErrUnsafe)
+		require.ErrorIs(t, archive.Add(config.File{Source: absolute, Destination: "bin/sh"}), destination.ErrUnsafe)
+		require.NoError(t, archive.Close())
+		require.Equal(t, map[string]string{
+			"bin/libfoo.so": "../lib/libfoo.so",
+		}, links(t, &buf))
+	})
+
+	t.Run("rewrite", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive := New(&buf, WithRewriteUnsafeSymlinks())
+		require.NoError(t, archive.Add(config.File{Source: safe, Destination: "bin/libfoo.so"}))
+		require.NoError(t, archive.Add(config.File{Source: escaping, Destination: "bin/escaping"}))
+		require.NoError(t, archive.Add(config.File{Source: absolute, Destination: "bin/sh"}))
+		require.NoError(t, archive.Close())
+		require.Equal(t, map[string]string{
+			"bin/libfoo.so": "../lib/libfoo.so",
+			"bin/escaping":  "../etc/passwd",
+			"bin/sh":        "../usr/bin/bash",
+		}, links(t, &buf))
+	})
+}
+
+func TestTarDirectCopy(t *testing.T) {
+	dir := t.TempDir()
+	content := make([]byte, 1_000_001)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(content)
+	files := []config.File{
+		{Source: filepath.Join(dir, "large.bin"), Destination: "large.bin"},
+		{Source: filepath.Join(dir, "empty.txt"), Destination: "empty.txt"},
+		{Source: "../testdata/foo.txt", Destination: strings.Repeat("a", 120) + "/foo.txt"},
+		{Source: "../testdata/sub1/bar.txt", Destination: "bar.txt"},
+	}
+	require.NoError(t, os.WriteFile(files[0].Source, content, 0o644))
+	require.NoError(t, os.WriteFile(files[1].Source, nil, 0o644))
+	for i := range files {
+		files[i].Info.ParsedMTime = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	// writing to a file takes the direct path, but the result must be the same.
+	f, err := os.Create(filepath.Join(dir, "direct.tar"))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	for _, w := range []io.Writer{f, &buf} {
+		archive := New(w)
+		for _, file := range files {
+			require.NoError(t, archive.Add(file))
+		}
+		require.NoError(t, addMapFile(t, archive, "fs.txt", &fstest.MapFile{Data: []byte("fs"), Mode: 0o644}))
+		require.NoError(t, archive.Close())
+	}
+	require.NoError(t, f.Close())
+
+	direct, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+	require.Equal(t, buf.Bytes(), direct)
+
+	r := tar.NewReader(bytes.NewReader(direct))
+	header, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, "large.bin", header.Name)
+	bts, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, content, bts)
+}
+
+func BenchmarkTarAdd(b *testing.B) {
+	dir := b.TempDir()
+	src := filepath.Join(dir, "large.bin")
+	content := make([]byte, 64*1024*1024)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(content)
+	require.NoError(b, os.WriteFile(src, content, 0o644))
+
+	for name, wrap := range map[string]func(*os.File) io.Writer{
+		"direct":   func(f *os.File) io.Writer { return f },
+		"buffered": func(f *os.File) io.Writer { return struct{ io.Writer }{f} },
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(content)))
+			for b.Loop() {
+				f, err := os.Create(filepath.Join(dir, "bench.tar"))
+				require.NoError(b, err)
+				archive := New(wrap(f))
+				require.NoError(b, archive.Add(config.File{
+					Source:      src,
+					Destination: "large.bin",
+				}))
+				require.NoError(b, archive.Close())
+				require.NoError(b, f.Close())
+			}
+		})
+	}
+}
+
+func TestTarResolveOwnerNames(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows has no numeric uids")
+	}
+	current, err := user.Current()
+	require.NoError(t, err)
+	group, err := user.LookupGroupId(current.Gid)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	archive := New(&buf, WithResolveOwnerNames())
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+		Info: config.FileInfo{
+			Owner: current.Username,
+			Group: group.Name,
+		},
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Destination: "dir",
+		Info: config.FileInfo{
+			Mode:  fs.ModeDir | 0o755,
+			Owner: current.Username,
+		},
+	}))
+	require.EqualError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "unknown-owner.txt",
+		Info:        config.FileInfo{Owner: "goreleaser-no-such-user"},
+	}), "unknown-owner.txt: could not resolve owner: user: unknown user goreleaser-no-such-user")
+	require.EqualError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "unknown-group.txt",
+		Info:        config.FileInfo{Group: "goreleaser-no-such-group"},
+	}), "unknown-group.txt: could not resolve group: group: unknown group goreleaser-no-such-group")
+	require.NoError(t, archive.Close())
+
+	uid, err := strconv.Atoi(current.Uid)
+	require.NoError(t, err)
+	gid, err := strconv.Atoi(current.Gid)
+	require.NoError(t, err)
+
+	r := tar.NewReader(&buf)
+	header, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, "foo.txt", header.Name)
+	require.Equal(t, uid, header.Uid)
+	require.Equal(t, current.Username, header.Uname)
+	require.Equal(t, gid, header.Gid)
+	require.Equal(t, group.Name, header.Gname)
+
+	header, err = r.Next()
+	require.NoError(t, err)
+	require.Equal(t, "dir/", header.Name)
+	require.Equal(t, uid, header.Uid)
+
+	_, err = r.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestTarDevices(t *testing.T) {
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	archive := New(&buf)
+	for _, f := range []config.File{
+		{
+			Destination: "dev/null",
+			Info: config.FileInfo{
+				Mode:        fs.ModeDevice | fs.ModeCharDevice | 0o666,
+				DevMajor:    1,
+				DevMinor:    3,
+				ParsedMTime: mtime,
+			},
+		},
+		{
+			Destination: "dev/sda",
+			Info: config.FileInfo{
+				Mode:        fs.ModeDevice | 0o660,
+				Owner:       "root",
+				Group:       "disk",
+				DevMajor:    8,
+				ParsedMTime: mtime,
+			},
+		},
+		{
+			Destination: "run/fifo",
+			Info: config.FileInfo{
+				Mode:        fs.ModeNamedPipe | 0o600,
+				DevMajor:    1,
+				ParsedMTime: mtime,
+			},
+		},
+	} {
+		require.NoError(t, archive.Add(f))
+	}
+	require.NoError(t, archive.Close())
+
+	var headers []tar.Header
+	r := tar.NewReader(&buf)
+	for {
+		header, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		headers = append(headers, tar.Header{
+			Typeflag: header.Typeflag,
+			Name:     header.Name,
+			Mode:     header.Mode,
+			Uname:    header.Uname,
+			Gname:    header.Gname,
+			Devmajor: header.Devmajor,
+			Devminor: header.Devminor,
+		})
+		require.True(t, mtime.Equal(header.ModTime))
+	}
+	require.Equal(t, []tar.Header{
+		{Typeflag: tar.TypeChar, Name: "dev/null", Mode: 0o666, Devmajor: 1, Devminor: 3},
+		{Typeflag: tar.TypeBlock, Name: "dev/sda", Mode: 0o660, Uname: "root", Gname: "disk", Devmajor: 8},
+		{Typeflag: tar.TypeFifo, Name: "run/fifo", Mode: 0o600},
+	}, headers)
+}
+
+func TestTarRecordSize(t *testing.T) {
+	for _, size := range []int{512, 10240, 20 * 1024} {
+		t.Run(strconv.Itoa(size), func(t *testing.T) {
+			var buf bytes.Buffer
+			archive := New(&buf, WithRecordSize(size))
+			require.NoError(t, archive.Add(config.File{
+				Source:      "../testdata/foo.txt",
+				Destination: "foo.txt",
+			}))
+			require.NoError(t, archive.Close())
+			require.Zero(t, buf.Len()%size)
+			require.GreaterOrEqual(t, buf.Len(), size)
+
+			r := tar.NewReader(&buf)
+			header, err := r.Next()
+			require.NoError(t, err)
+			require.Equal(t, "foo.txt", header.Name)
+			_, err = r.Next()
+			require.ErrorIs(t, err, io.EOF)
+		})
+	}
+
+	t.Run("file", func(t *testing.T) {
+		f, err := os.Create(filepath.Join(t.TempDir(), "test.tar"))
+		require.NoError(t, err)
+		defer f.Close()
+		archive := New(f, WithRecordSize(10240))
+		require.NoError(t, archive.Add(config.File{
+			Source:      "../testdata/foo.txt",
+			Destination: "foo.txt",
+		}))
+		require.NoError(t, archive.Close())
+		info, err := f.Stat()
+		require.NoError(t, err)
+		require.Equal(t, int64(10240), info.Size())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		archive := New(io.Discard, WithRecordSize(1000))
+		require.EqualError(t, archive.Close(), "invalid record size 1000: must be a multiple of 512")
+	})
+}
@@ -0,0 +1,51 @@
+package tar
+
+import (
+	"bytes"
+	"errors"
+	"syscall"
+)
+
+// xattrs returns the extended attributes of the given file.
+//
+// File systems which don't support extended attributes are treated as if the
+// file had none.
+func xattrs(name string) (map[string]string, error) {
+	size, err := syscall.Listxattr(name, nil)
+	if errors.Is(err, syscall.ENOTSUP) || size == 0 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(name, buf)
+	if err != nil {
+		return nil, err
+	}
+	attrs := map[string]string{}
+	for _, key := range bytes.Split(buf[:size], []byte{0}) {
+		if len(key) == 0 {
+			continue
+		}
+		value, err := getxattr(name, string(key))
+		if err != nil {
+			return nil, err
+		}
+		attrs[string(key)] = value
+	}
+	return attrs, nil
+}
+
+func getxattr(name, key string) (string, error) {
+	size, err := syscall.Getxattr(name, key, nil)
+	if err != nil || size == 0 {
+		return "", err
+	}
+	buf := make([]byte, size)
+	size, err = syscall.Getxattr(name, key, buf)
+	if err != nil {
+		return "", err
+	}
+	return string(buf[:size]), nil
+}
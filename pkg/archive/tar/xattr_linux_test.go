@@ -0,0 +1,54 @@
+package tar
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarXattrs(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "app")
+	// a sparse file, with some data at the beginning.
+	f, err := os.Create(src)
+	require.NoError(t, err)
+	_, err = f.WriteString("app")
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(1024*1024))
+	require.NoError(t, f.Close())
+	if err := syscall.Setxattr(src, "user.goreleaser", []byte("rocks"), 0); err != nil {
+		t.Skipf("file system does not support extended attributes: %v", err)
+	}
+
+	for name, opts := range map[string][]Option{
+		"regular": {WithXattrs()},
+		"sparse":  {WithXattrs(), WithSparseFiles()},
+		"off":     nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			archive := New(&buf, opts...)
+			require.NoError(t, archive.Add(config.File{
+				Source:      src,
+				Destination: "app",
+			}))
+			require.NoError(t, archive.Close())
+
+			r := tar.NewReader(&buf)
+			next, err := r.Next()
+			require.NoError(t, err)
+			require.Equal(t, "app", next.Name)
+			if opts == nil {
+				require.NotContains(t, next.PAXRecords, "SCHILY.xattr.user.goreleaser")
+				return
+			}
+			require.Equal(t, "rocks", next.PAXRecords["SCHILY.xattr.user.goreleaser"])
+		})
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package tar
+
+// xattrs always returns nil, as reading extended attributes is only
+// supported on Linux.
+func xattrs(string) (map[string]string, error) {
+	return nil, nil
+}
@@ -5,6 +5,7 @@ package targz
 import (
 	"compress/gzip"
 	"io"
+	"io/fs"
 
 	"github.com/goreleaser/goreleaser/v2/pkg/archive/tar"
 	"github.com/goreleaser/goreleaser/v2/pkg/config"
@@ -17,30 +18,63 @@ type Archive struct {
 }
 
 // New tar.gz archive.
-func New(target io.Writer) Archive {
+func New(target io.Writer, opts ...tar.Option) Archive {
 	// the error will be nil since the compression level is valid
 	gw, _ := gzip.NewWriterLevel(target, gzip.BestCompression)
-	tw := tar.New(gw)
+	tw := tar.New(gw, opts...)
 	return Archive{
 		gw: gw,
 		tw: &tw,
 	}
 }
 
-func Copy(source io.Reader, target io.Writer) (Archive, error) {
+// NewWithLevel creates a tar.gz archive using the given compression level,
+// from [gzip.HuffmanOnly] to [gzip.BestCompression], which [New] uses.
+func NewWithLevel(target io.Writer, level int, opts ...tar.Option) (Archive, error) {
+	gw, err := gzip.NewWriterLevel(target, level)
+	if err != nil {
+		return Archive{}, err
+	}
+	tw := tar.New(gw, opts...)
+	return Archive{
+		gw: gw,
+		tw: &tw,
+	}, nil
+}
+
+// Copy copies the entries of the source tar.gz archive into a new one, which
+// can be appended to.
+//
+// Entries are streamed from the source as they are decompressed, so memory
+// usage does not grow with the size of the archive.
+func Copy(source io.Reader, target io.Writer, opts ...tar.Option) (Archive, error) {
 	// the error will be nil since the compression level is valid
 	gw, _ := gzip.NewWriterLevel(target, gzip.BestCompression)
 	srcgz, err := gzip.NewReader(source)
 	if err != nil {
 		return Archive{}, err
 	}
-	tw, err := tar.Copy(srcgz, gw)
+	tw, err := tar.Copy(srcgz, gw, opts...)
 	return Archive{
 		gw: gw,
 		tw: &tw,
 	}, err
 }
 
+// Format returns the archive format, "tar.gz".
+func (a Archive) Format() string {
+	return "tar.gz"
+}
+
+// Flush writes any buffered data, including the compressor state, to the
+// underlying writer, so everything added so far can be decompressed.
+func (a Archive) Flush() error {
+	if err := a.tw.Flush(); err != nil {
+		return err
+	}
+	return a.gw.Flush()
+}
+
 // Close all closeables.
 func (a Archive) Close() error {
 	if err := a.tw.Close(); err != nil {
@@ -53,3 +87,10 @@ func (a Archive) Close() error {
 func (a Archive) Add(f config.File) error {
 	return a.tw.Add(f)
 }
+
+// AddWithReader adds a regular file to the archive, reading its content from
+// r, with the size, mode and modification time of the given info, unless set
+// in the file info.
+func (a Archive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	return a.tw.AddWithReader(f, info, r)
+}
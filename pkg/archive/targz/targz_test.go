@@ -2,11 +2,15 @@ package targz
 
 import (
 	"archive/tar"
+	"bytes"
 	"compress/gzip"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
@@ -166,3 +170,104 @@ func TestTarGzFileInfo(t *testing.T) {
 	}
 	require.Equal(t, 1, found)
 }
+
+func TestTarGzFlush(t *testing.T) {
+	var buf bytes.Buffer
+	archive := New(&buf)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	before := buf.Len()
+	require.NoError(t, archive.Flush())
+	require.Greater(t, buf.Len(), before)
+
+	// the archive isn't closed yet, but what was added so far can be read.
+	gzf, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	r := tar.NewReader(gzf)
+	next, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, "foo.txt", next.Name)
+	bts, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "foo\n", string(bts))
+
+	require.NoError(t, archive.Close())
+}
+
+// largeTarGz returns a tar.gz archive with the given number of entries, each
+// with size bytes of zeroes, which compress really well.
+func largeTarGz(tb testing.TB, entries int, size int64) []byte {
+	tb.Helper()
+	var buf bytes.Buffer
+	gw, err := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+	require.NoError(tb, err)
+	tw := tar.NewWriter(gw)
+	for i := range entries {
+		require.NoError(tb, tw.WriteHeader(&tar.Header{
+			Name: fmt.Sprintf("file%d.bin", i),
+			Mode: 0o644,
+			Size: size,
+		}))
+		_, err := io.CopyN(tw, zeroes{}, size)
+		require.NoError(tb, err)
+	}
+	require.NoError(tb, tw.Close())
+	require.NoError(tb, gw.Close())
+	return buf.Bytes()
+}
+
+type zeroes struct{}
+
+func (zeroes) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestTarGzCopyStreams(t *testing.T) {
+	const entries, size = 8, 8 * 1024 * 1024
+	source := largeTarGz(t, entries, size)
+
+	var out bytes.Buffer
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	archive, err := Copy(bytes.NewReader(source), &out)
+	require.NoError(t, err)
+	require.NoError(t, archive.Close())
+	runtime.ReadMemStats(&after)
+
+	// the whole archive is 64MB once decompressed, but only the compressors
+	// state and the copy buffer should be allocated, plus the (tiny) output.
+	allocated := after.TotalAlloc - before.TotalAlloc
+	require.Less(t, allocated, uint64(size), "allocated %d bytes", allocated)
+
+	gr, err := gzip.NewReader(&out)
+	require.NoError(t, err)
+	r := tar.NewReader(gr)
+	var count int
+	for {
+		header, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, int64(size), header.Size)
+		n, err := io.Copy(io.Discard, r)
+		require.NoError(t, err)
+		require.Equal(t, int64(size), n)
+		count++
+	}
+	require.Equal(t, entries, count)
+}
+
+func BenchmarkCopy(b *testing.B) {
+	source := largeTarGz(b, 8, 8*1024*1024)
+	b.ReportAllocs()
+	for b.Loop() {
+		archive, err := Copy(bytes.NewReader(source), io.Discard)
+		require.NoError(b, err)
+		require.NoError(b, archive.Close())
+	}
+}
@@ -3,8 +3,15 @@
 package tarxz
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"os/exec"
+	"strconv"
 
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/closed"
 	"github.com/goreleaser/goreleaser/v2/pkg/archive/tar"
 	"github.com/goreleaser/goreleaser/v2/pkg/config"
 	"github.com/ulikunitz/xz"
@@ -12,29 +19,116 @@ import (
 
 // Archive as tar.xz.
 type Archive struct {
-	xzw *xz.Writer
-	tw  *tar.Archive
+	xzw    io.WriteCloser
+	tw     *tar.Archive
+	closed *closed.Flag
 }
 
 // New tar.xz archive.
-func New(target io.Writer) Archive {
+func New(target io.Writer, opts ...tar.Option) Archive {
 	xzw, _ := xz.WriterConfig{DictCap: 16 * 1024 * 1024}.NewWriter(target)
-	tw := tar.New(xzw)
+	tw := tar.New(xzw, opts...)
 	return Archive{
-		xzw: xzw,
-		tw:  &tw,
+		xzw:    xzw,
+		tw:     &tw,
+		closed: &closed.Flag{},
 	}
 }
 
+// NewWithThreads creates a tar.xz archive compressed using the given number of
+// threads.
+//
+// The pure Go xz implementation is single-threaded, so, if threads is greater
+// than 1, the `xz` binary is used instead, which must be in the $PATH:
+// otherwise, an error wrapping [exec.ErrNotFound] is returned, and callers
+// can fall back to [New].
+//
+// The output of the `xz` binary depends on its version and default preset, so
+// archives created with more than 1 thread are not reproducible across hosts.
+//
+// The archive must be closed, so the `xz` process is waited for.
+func NewWithThreads(target io.Writer, threads int, opts ...tar.Option) (Archive, error) {
+	if threads <= 1 {
+		return New(target, opts...), nil
+	}
+	bin, err := exec.LookPath("xz")
+	if err != nil {
+		return Archive{}, fmt.Errorf("xz: %d threads need the xz binary: %w", threads, err)
+	}
+	xzw, err := newXzCmd(bin, threads, target)
+	if err != nil {
+		return Archive{}, err
+	}
+	tw := tar.New(xzw, opts...)
+	return Archive{
+		xzw:    xzw,
+		tw:     &tw,
+		closed: &closed.Flag{},
+	}, nil
+}
+
+// Format returns the archive format, "tar.xz".
+func (a Archive) Format() string {
+	return "tar.xz"
+}
+
 // Close all closeables.
+//
+// The compressor is always closed, even if closing the tar archive fails, so
+// the `xz` process, if any, is waited for.
 func (a Archive) Close() error {
-	if err := a.tw.Close(); err != nil {
+	if err := a.closed.Close(); err != nil {
 		return err
 	}
-	return a.xzw.Close()
+	return errors.Join(a.tw.Close(), a.xzw.Close())
 }
 
 // Add file to the archive.
 func (a Archive) Add(f config.File) error {
 	return a.tw.Add(f)
 }
+
+// AddWithReader adds a regular file to the archive, reading its content from
+// r, with the size, mode and modification time of the given info, unless set
+// in the file info.
+func (a Archive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	return a.tw.AddWithReader(f, info, r)
+}
+
+// xzCmd compresses everything written to it using the xz binary.
+type xzCmd struct {
+	io.WriteCloser
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+}
+
+func newXzCmd(bin string, threads int, target io.Writer) (*xzCmd, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(bin, "--compress", "--stdout", "-T"+strconv.Itoa(threads))
+	cmd.Stdout = target
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("xz: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("xz: could not start %s: %w", bin, err)
+	}
+	return &xzCmd{
+		WriteCloser: stdin,
+		cmd:         cmd,
+		stderr:      &stderr,
+	}, nil
+}
+
+// Close closes the input and waits for the command to finish.
+func (x *xzCmd) Close() error {
+	closeErr := x.WriteCloser.Close()
+	if err := x.cmd.Wait(); err != nil {
+		return fmt.Errorf("xz: %w: %s", err, x.stderr.String())
+	}
+	if closeErr != nil {
+		return fmt.Errorf("xz: %w", closeErr)
+	}
+	return nil
+}
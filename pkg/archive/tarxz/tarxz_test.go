@@ -2,14 +2,18 @@ package tarxz
 
 import (
 	"archive/tar"
+	"errors"
 	"io"
 	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/goreleaser/goreleaser/v2/internal/testlib"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/closed"
 	"github.com/goreleaser/goreleaser/v2/pkg/config"
 	"github.com/stretchr/testify/require"
 	"github.com/ulikunitz/xz"
@@ -165,3 +169,75 @@ func TestTarXzFileInfo(t *testing.T) {
 	}
 	require.Equal(t, 1, found)
 }
+
+func TestTarXzThreads(t *testing.T) {
+	for _, threads := range []int{0, 1, 4} {
+		t.Run(strconv.Itoa(threads), func(t *testing.T) {
+			if _, err := exec.LookPath("xz"); err != nil && threads > 1 {
+				t.Skip("xz not available")
+			}
+			f, err := os.Create(filepath.Join(t.TempDir(), "test.tar.xz"))
+			require.NoError(t, err)
+			defer f.Close()
+			archive, err := NewWithThreads(f, threads)
+			require.NoError(t, err)
+			require.NoError(t, archive.Add(config.File{
+				Source:      "../testdata/foo.txt",
+				Destination: "foo.txt",
+			}))
+			require.NoError(t, archive.Add(config.File{
+				Source:      "../testdata/sub1/bar.txt",
+				Destination: "sub1/bar.txt",
+			}))
+			require.NoError(t, archive.Close())
+			require.NoError(t, f.Close())
+
+			require.Equal(t, []string{"foo.txt", "sub1/bar.txt"}, testlib.LsArchive(t, f.Name(), "tar.xz"))
+			require.Equal(
+				t,
+				[]byte("foo\n"),
+				testlib.GetFileFromArchive(t, f.Name(), "tar.xz", "foo.txt"),
+			)
+		})
+	}
+}
+
+func TestTarXzThreadsCloseError(t *testing.T) {
+	if _, err := exec.LookPath("xz"); err != nil {
+		t.Skip("xz not available")
+	}
+	archive, err := NewWithThreads(failWriter{}, 4)
+	require.NoError(t, err)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	require.ErrorIs(t, archive.Close(), errFail)
+}
+
+func TestTarXzThreadsNoXz(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	_, err := NewWithThreads(io.Discard, 4)
+	require.ErrorIs(t, err, exec.ErrNotFound)
+	require.ErrorContains(t, err, "xz: 4 threads need the xz binary")
+}
+
+func TestTarXzCloseTwice(t *testing.T) {
+	for _, threads := range []int{1, 4} {
+		t.Run(strconv.Itoa(threads), func(t *testing.T) {
+			if _, err := exec.LookPath("xz"); err != nil && threads > 1 {
+				t.Skip("xz not available")
+			}
+			archive, err := NewWithThreads(io.Discard, threads)
+			require.NoError(t, err)
+			require.NoError(t, archive.Close())
+			require.ErrorIs(t, archive.Close(), closed.ErrClosed)
+		})
+	}
+}
+
+var errFail = errors.New("fail")
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errFail }
@@ -3,7 +3,10 @@
 package tarzst
 
 import (
+	"encoding/binary"
+	"fmt"
 	"io"
+	"io/fs"
 
 	"github.com/goreleaser/goreleaser/v2/pkg/archive/tar"
 	"github.com/goreleaser/goreleaser/v2/pkg/config"
@@ -17,15 +20,94 @@ type Archive struct {
 }
 
 // New tar.zst archive.
-func New(target io.Writer) Archive {
+func New(target io.Writer, opts ...tar.Option) Archive {
 	zstw, _ := zstd.NewWriter(target)
-	tw := tar.New(zstw)
+	tw := tar.New(zstw, opts...)
 	return Archive{
 		zstw: zstw,
 		tw:   &tw,
 	}
 }
 
+// Options customizes the zstd encoder used by [NewWithOptions].
+type Options struct {
+	// Long is the base 2 logarithm of the window size, allowing matches
+	// farther apart than the default 8MB window, at the cost of memory.
+	// Zero keeps the default window size.
+	Long int
+
+	// Dict is a dictionary, as created by `zstd --train` or [zstd.BuildDict],
+	// used to compress the archive.
+	// The same dictionary is needed to decompress it.
+	Dict []byte
+
+	// Level is the compression level, from 1 to 22, like the ones of the
+	// zstd command, which are mapped to the closest supported level.
+	// Zero keeps the default level.
+	Level int
+}
+
+// NewWithOptions creates a tar.zst archive using the given encoder options.
+func NewWithOptions(target io.Writer, o Options, opts ...tar.Option) (Archive, error) {
+	var eopts []zstd.EOption
+	if o.Long > 0 {
+		eopts = append(eopts, zstd.WithWindowSize(1<<o.Long))
+	}
+	if len(o.Dict) > 0 {
+		eopts = append(eopts, zstd.WithEncoderDict(o.Dict))
+	}
+	if o.Level > 0 {
+		eopts = append(eopts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(o.Level)))
+	}
+	zstw, err := zstd.NewWriter(target, eopts...)
+	if err != nil {
+		return Archive{}, fmt.Errorf("zstd: %w", err)
+	}
+	tw := tar.New(zstw, opts...)
+	return Archive{
+		zstw: zstw,
+		tw:   &tw,
+	}, nil
+}
+
+// manifestMagic is the magic number of the skippable frame written by
+// [NewWithManifest].
+// Skippable frames can use any magic number from 0x184D2A50 to 0x184D2A5F.
+const manifestMagic = 0x184D2A50
+
+// NewWithManifest creates a tar.zst archive starting with a zstd skippable
+// frame holding the given manifest, e.g. a small JSON document with the
+// version of the tool and the ID of the build which created it.
+//
+// Standard zstd decoders ignore skippable frames, so the archive decompresses
+// as usual; the manifest can be read back by looking for the frame at the
+// start of the stream.
+func NewWithManifest(target io.Writer, manifest []byte, opts ...tar.Option) (Archive, error) {
+	if uint64(len(manifest)) > 0xffffffff {
+		return Archive{}, fmt.Errorf("zstd: manifest too large: %d bytes", len(manifest))
+	}
+	header := binary.LittleEndian.AppendUint32(nil, manifestMagic)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(manifest)))
+	if _, err := target.Write(append(header, manifest...)); err != nil {
+		return Archive{}, fmt.Errorf("zstd: %w", err)
+	}
+	return New(target, opts...), nil
+}
+
+// Format returns the archive format, "tar.zst".
+func (a Archive) Format() string {
+	return "tar.zst"
+}
+
+// Flush writes any buffered data, including the compressor state, to the
+// underlying writer, so everything added so far can be decompressed.
+func (a Archive) Flush() error {
+	if err := a.tw.Flush(); err != nil {
+		return err
+	}
+	return a.zstw.Flush()
+}
+
 // Close all closeables.
 func (a Archive) Close() error {
 	if err := a.tw.Close(); err != nil {
@@ -38,3 +120,10 @@ func (a Archive) Close() error {
 func (a Archive) Add(f config.File) error {
 	return a.tw.Add(f)
 }
+
+// AddWithReader adds a regular file to the archive, reading its content from
+// r, with the size, mode and modification time of the given info, unless set
+// in the file info.
+func (a Archive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	return a.tw.AddWithReader(f, info, r)
+}
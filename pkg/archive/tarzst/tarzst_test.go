@@ -2,10 +2,15 @@ package tarzst
 
 import (
 	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"fmt"
 	"io"
 	"io/fs"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -165,3 +170,164 @@ func TestTarZstFileInfo(t *testing.T) {
 	}
 	require.Equal(t, 1, found)
 }
+
+func TestTarZstLong(t *testing.T) {
+	chunk := make([]byte, 9*1024*1024)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(chunk)
+	src := filepath.Join(t.TempDir(), "repetitive.bin")
+	require.NoError(t, os.WriteFile(src, append(chunk, chunk...), 0o644))
+
+	compress := func(tb testing.TB, o Options) []byte {
+		tb.Helper()
+		var buf bytes.Buffer
+		archive, err := NewWithOptions(&buf, o)
+		require.NoError(tb, err)
+		require.NoError(tb, archive.Add(config.File{
+			Source:      src,
+			Destination: "repetitive.bin",
+		}))
+		require.NoError(tb, archive.Close())
+		return buf.Bytes()
+	}
+
+	short := compress(t, Options{})
+	long := compress(t, Options{Long: 25})
+	require.Less(t, len(long), len(short)*3/4)
+
+	zstf, err := zstd.NewReader(bytes.NewReader(long))
+	require.NoError(t, err)
+	defer zstf.Close()
+	r := tar.NewReader(zstf)
+	next, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, "repetitive.bin", next.Name)
+	bts, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, append(chunk, chunk...), bts)
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewWithOptions(io.Discard, Options{Long: 40})
+		require.ErrorContains(t, err, "zstd: window size must be at most")
+	})
+}
+
+func TestTarZstDict(t *testing.T) {
+	var contents [][]byte
+	for i := range 64 {
+		contents = append(contents, []byte(strings.Repeat(fmt.Sprintf("goreleaser sample %d\n", i), 32)))
+	}
+	dict, err := zstd.BuildDict(zstd.BuildDictOptions{
+		ID:       1,
+		Contents: contents,
+		History:  []byte(strings.Repeat("goreleaser sample\n", 256)),
+		Offsets:  [3]int{1, 4, 8},
+	})
+	require.NoError(t, err)
+
+	for name, o := range map[string]Options{
+		"with dict":    {Dict: dict},
+		"without dict": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			archive, err := NewWithOptions(&buf, o)
+			require.NoError(t, err)
+			require.NoError(t, archive.Add(config.File{
+				Source:      "../testdata/foo.txt",
+				Destination: "foo.txt",
+			}))
+			require.NoError(t, archive.Close())
+
+			if len(o.Dict) > 0 {
+				zstf, err := zstd.NewReader(bytes.NewReader(buf.Bytes()))
+				require.NoError(t, err)
+				defer zstf.Close()
+				_, err = io.ReadAll(zstf)
+				require.Error(t, err)
+			}
+
+			zstf, err := zstd.NewReader(bytes.NewReader(buf.Bytes()), zstd.WithDecoderDicts(dict))
+			require.NoError(t, err)
+			defer zstf.Close()
+			r := tar.NewReader(zstf)
+			next, err := r.Next()
+			require.NoError(t, err)
+			require.Equal(t, "foo.txt", next.Name)
+			bts, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, "foo\n", string(bts))
+		})
+	}
+}
+
+func TestTarZstFlush(t *testing.T) {
+	var buf bytes.Buffer
+	archive := New(&buf)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	before := buf.Len()
+	require.NoError(t, archive.Flush())
+	require.Greater(t, buf.Len(), before)
+
+	// the archive isn't closed yet, but what was added so far can be read.
+	zstf, err := zstd.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	defer zstf.Close()
+	r := tar.NewReader(zstf)
+	next, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, "foo.txt", next.Name)
+	bts, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "foo\n", string(bts))
+
+	require.NoError(t, archive.Close())
+}
+
+func TestTarZstManifest(t *testing.T) {
+	manifest := []byte(`{"version":"v2.0.0","build":"abc123"}`)
+	var buf bytes.Buffer
+	archive, err := NewWithManifest(&buf, manifest)
+	require.NoError(t, err)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	require.NoError(t, archive.Close())
+
+	t.Run("decompresses", func(t *testing.T) {
+		zstf, err := zstd.NewReader(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		defer zstf.Close()
+		r := tar.NewReader(zstf)
+		next, err := r.Next()
+		require.NoError(t, err)
+		require.Equal(t, "foo.txt", next.Name)
+		bts, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, "foo\n", string(bts))
+		_, err = r.Next()
+		require.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("recover", func(t *testing.T) {
+		r := bytes.NewReader(buf.Bytes())
+		var header [2]uint32
+		require.NoError(t, binary.Read(r, binary.LittleEndian, &header))
+		require.Equal(t, uint32(0x184D2A50), header[0]&0xfffffff0)
+		got := make([]byte, header[1])
+		_, err := io.ReadFull(r, got)
+		require.NoError(t, err)
+		require.Equal(t, manifest, got)
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		f, err := os.Create(filepath.Join(t.TempDir(), "test.tar.zst"))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		_, err = NewWithManifest(f, manifest)
+		require.ErrorIs(t, err, os.ErrClosed)
+	})
+}
@@ -0,0 +1,37 @@
+package archive
+
+import (
+	"io"
+	"io/fs"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// WithNameTransform makes the destination of every entry, including its
+// prefix, be transformed by the given function before being added, e.g. to
+// enforce naming conventions, like lowercase names.
+//
+// Duplicates are checked after the transformation, so adding two files whose
+// transformed destinations collide fails.
+func WithNameTransform(transform func(string) string) Option {
+	return func(o *options) {
+		o.transform = transform
+	}
+}
+
+type transformArchive struct {
+	Archive
+	transform func(string) string
+}
+
+func (a transformArchive) unwrap() Archive { return a.Archive }
+
+func (a transformArchive) Add(f config.File) error {
+	f.Destination = a.transform(f.Destination)
+	return a.Archive.Add(f)
+}
+
+func (a transformArchive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	f.Destination = a.transform(f.Destination)
+	return addReader(a.Archive, f, info, r)
+}
@@ -0,0 +1,54 @@
+package archive
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goreleaser/goreleaser/v2/internal/testlib"
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNameTransform(t *testing.T) {
+	transform := func(name string) string {
+		return strings.ReplaceAll(strings.ToLower(name), " ", "_")
+	}
+	for _, format := range []string{"tar.gz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "archive."+format)
+			f, err := os.Create(path)
+			require.NoError(t, err)
+			archive, err := New(f, format, WithPrefix("MyApp"), WithNameTransform(transform))
+			require.NoError(t, err)
+			require.NoError(t, archive.Add(config.File{Source: "testdata/foo.txt", Destination: "README.md"}))
+			require.NoError(t, archive.Add(config.File{Source: "testdata/foo.txt", Destination: "Release Notes.txt"}))
+			require.NoError(t, archive.Add(config.File{
+				Destination: "Logs",
+				Info:        config.FileInfo{Mode: fs.ModeDir | 0o755},
+			}))
+			require.ErrorIs(t, archive.Add(config.File{
+				Source:      "testdata/foo.txt",
+				Destination: "readme.md",
+			}), fs.ErrExist)
+			require.NoError(t, AddFS(archive, fstest.MapFS{
+				"Sub Dir/Bar.txt": {Data: []byte("bar")},
+			}, "Docs"))
+			require.ErrorIs(t, AddFS(archive, fstest.MapFS{
+				"SUB DIR/BAR.TXT": {Data: []byte("bar")},
+			}, "docs"), fs.ErrExist)
+			require.NoError(t, archive.Close())
+			require.NoError(t, f.Close())
+
+			require.ElementsMatch(t, []string{
+				"myapp/readme.md",
+				"myapp/release_notes.txt",
+				"myapp/logs/",
+				"myapp/docs/sub_dir/bar.txt",
+			}, testlib.LsArchive(t, path, format))
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// WithURLSources makes sources which are HTTP(S) URLs be downloaded with the
+// given client, or [http.DefaultClient] if nil, streaming them into the
+// archive.
+//
+// As archives need to know the size of their entries up front, responses
+// without a Content-Length are read into memory first.
+// The modification time defaults to the Last-Modified of the response.
+// Responses can only be read once, so they can't be used with
+// [WithDuplicates], unless read into memory.
+func WithURLSources(client *http.Client) Option {
+	return func(o *options) {
+		if client == nil {
+			client = http.DefaultClient
+		}
+		o.httpClient = client
+	}
+}
+
+// urlArchive downloads the sources which are HTTP(S) URLs.
+type urlArchive struct {
+	Archive
+	client *http.Client
+}
+
+func (a urlArchive) unwrap() Archive { return a.Archive }
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+func (a urlArchive) Add(f config.File) error {
+	if !isURL(f.Source) {
+		return a.Archive.Add(f)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, f.Source, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status: %s", f.Source, resp.Status)
+	}
+
+	info := readerInfo{
+		name:  path.Base(f.Destination),
+		size:  resp.ContentLength,
+		mode:  f.Info.Mode.Perm(),
+		mtime: f.Info.ParsedMTime,
+	}
+	if info.mode == 0 {
+		info.mode = 0o644
+	}
+	if info.mtime.IsZero() {
+		info.mtime, err = http.ParseTime(resp.Header.Get("Last-Modified"))
+		if err != nil {
+			info.mtime = time.Now()
+		}
+	}
+	var r io.Reader = resp.Body
+	if info.size < 0 {
+		bts, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Source, err)
+		}
+		info.size = int64(len(bts))
+		r = bytes.NewReader(bts)
+	}
+	source := f.Source
+	f.Source = ""
+	if err := addReader(a.Archive, f, info, r); err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	return nil
+}
+
+func (a urlArchive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	return addReader(a.Archive, f, info, r)
+}
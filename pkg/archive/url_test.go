@@ -0,0 +1,98 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goreleaser/goreleaser/v2/internal/testlib"
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLSources(t *testing.T) {
+	payload := strings.Repeat("some payload\n", 1000)
+	modified := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blob":
+			http.ServeContent(w, r, "blob", modified, strings.NewReader(payload))
+		case "/chunked":
+			_, _ = io.WriteString(w, payload[:100])
+			w.(http.Flusher).Flush()
+			_, _ = io.WriteString(w, payload[100:])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	var buf bytes.Buffer
+	archive, err := New(&buf, "tar", WithURLSources(srv.Client()))
+	require.NoError(t, err)
+	require.NoError(t, archive.Add(config.File{
+		Source:      srv.URL + "/blob",
+		Destination: "bin/blob",
+		Info:        config.FileInfo{Mode: 0o755},
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      srv.URL + "/chunked",
+		Destination: "chunked",
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	require.EqualError(t, archive.Add(config.File{
+		Source:      srv.URL + "/missing",
+		Destination: "missing",
+	}), srv.URL+"/missing: unexpected status: 404 Not Found")
+	require.NoError(t, archive.Close())
+
+	var headers []*tar.Header
+	r := tar.NewReader(&buf)
+	for {
+		header, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		headers = append(headers, header)
+		if header.Name != "foo.txt" {
+			bts, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, payload, string(bts))
+		}
+	}
+	require.Len(t, headers, 3)
+	require.Equal(t, "bin/blob", headers[0].Name)
+	require.Equal(t, int64(0o755), headers[0].Mode)
+	require.True(t, modified.Equal(headers[0].ModTime))
+	require.Equal(t, "chunked", headers[1].Name)
+	require.Equal(t, int64(0o644), headers[1].Mode)
+	require.Equal(t, "foo.txt", headers[2].Name)
+
+	t.Run("zip", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.zip")
+		require.NoError(t, CreateArchive(path, "zip", []config.File{
+			{Source: srv.URL + "/blob", Destination: "blob"},
+		}, WithURLSources(nil)))
+		require.Equal(t, []string{"blob"}, testlib.LsArchive(t, path, "zip"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		archive, err := New(io.Discard, "tar")
+		require.NoError(t, err)
+		require.Error(t, archive.Add(config.File{
+			Source:      srv.URL + "/blob",
+			Destination: "blob",
+		}))
+	})
+}
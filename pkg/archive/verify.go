@@ -0,0 +1,62 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrLimitExceeded happens when an archive is larger than the [Limits] given
+// to [VerifyWithLimits].
+var ErrLimitExceeded = errors.New("archive limit exceeded")
+
+// Limits bounds how much of an archive is read, protecting against
+// decompression bombs.
+// Zero values mean no limit.
+type Limits struct {
+	// MaxBytes is the maximum number of decompressed bytes.
+	MaxBytes int64
+
+	// MaxEntries is the maximum number of entries.
+	MaxEntries int
+}
+
+// Verify reads the archive in the given format end-to-end, checking that every
+// entry can be decompressed and matches its recorded size and checksum,
+// returning the first corruption found.
+//
+// Zip archives are read fully into memory, as their central directory is at
+// the end of the file.
+func Verify(r io.Reader, format string) error {
+	return VerifyWithLimits(r, format, Limits{})
+}
+
+// VerifyWithLimits works like [Verify], but fails with [ErrLimitExceeded] as
+// soon as the archive goes over the given limits.
+func VerifyWithLimits(r io.Reader, format string, limits Limits) error {
+	return walkEntries(r, format, "verify", limits, func(_ string, content io.Reader) error {
+		_, err := io.Copy(io.Discard, content)
+		return err
+	})
+}
+
+// limitReader fails with [ErrLimitExceeded] once more than limit bytes are
+// read from it, if limit is greater than zero.
+type limitReader struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func newLimitReader(r io.Reader, limit int64) *limitReader {
+	return &limitReader{r: r, limit: limit}
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.limit > 0 && l.n > l.limit {
+		return n, fmt.Errorf("%w: more than %d bytes", ErrLimitExceeded, l.limit)
+	}
+	return n, err
+}
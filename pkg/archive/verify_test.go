@@ -0,0 +1,130 @@
+package archive
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	content := make([]byte, 64*1024)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(content)
+	src := filepath.Join(t.TempDir(), "random.bin")
+	require.NoError(t, os.WriteFile(src, content, 0o644))
+
+	build := func(tb testing.TB, format string) []byte {
+		tb.Helper()
+		var buf bytes.Buffer
+		archive, err := New(&buf, format)
+		require.NoError(tb, err)
+		require.NoError(tb, archive.Add(config.File{
+			Source:      src,
+			Destination: "random.bin",
+		}))
+		require.NoError(tb, archive.Close())
+		return buf.Bytes()
+	}
+
+	for _, format := range []string{"tar", "tar.gz", "tgz", "tar.xz", "txz", "tar.zst", "tzst", "gz", "zip", "ar", "cpio"} {
+		t.Run(format, func(t *testing.T) {
+			bts := build(t, format)
+			require.NoError(t, Verify(bytes.NewReader(bts), format))
+
+			t.Run("truncated", func(t *testing.T) {
+				require.Error(t, Verify(bytes.NewReader(bts[:len(bts)/2]), format))
+			})
+		})
+	}
+
+	t.Run("corrupted tar.gz", func(t *testing.T) {
+		bts := build(t, "tar.gz")
+		bts[len(bts)/2] ^= 0xff
+		require.Error(t, Verify(bytes.NewReader(bts), "tar.gz"))
+	})
+
+	t.Run("corrupted zip", func(t *testing.T) {
+		bts := build(t, "zip")
+		bts[len(bts)/2] ^= 0xff
+		require.ErrorContains(t, Verify(bytes.NewReader(bts), "zip"), "random.bin: ")
+	})
+
+	t.Run("registered", func(t *testing.T) {
+		Register("fake", func(w io.Writer) Archive {
+			return &fakeArchive{w: w}
+		})
+		t.Cleanup(func() {
+			registryMu.Lock()
+			defer registryMu.Unlock()
+			delete(registry, "fake")
+		})
+		require.EqualError(t, Verify(bytes.NewReader(nil), "fake"), "verify not supported for archive format: fake")
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		require.EqualError(t, Verify(bytes.NewReader(nil), "7z"), "invalid archive format: 7z")
+	})
+}
+
+func TestVerifyWithLimits(t *testing.T) {
+	// a file full of zeroes compresses really well.
+	bomb := filepath.Join(t.TempDir(), "bomb.bin")
+	f, err := os.Create(bomb)
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(4*1024*1024))
+	require.NoError(t, f.Close())
+
+	var files []config.File
+	files = append(files, config.File{Source: bomb, Destination: "bomb.bin"})
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		files = append(files, config.File{Source: "testdata/foo.txt", Destination: name})
+	}
+
+	for _, format := range []string{"tar", "tar.gz", "tar.xz", "tar.zst", "gz", "zip", "ar", "cpio"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			archive, err := New(&buf, format)
+			require.NoError(t, err)
+			require.NoError(t, archive.Add(files[0]))
+			if format != "gz" {
+				for _, f := range files[1:] {
+					require.NoError(t, archive.Add(f))
+				}
+			}
+			require.NoError(t, archive.Close())
+			bts := buf.Bytes()
+			switch format {
+			case "tar", "ar", "cpio":
+			default:
+				require.Less(t, len(bts), 64*1024)
+			}
+
+			require.NoError(t, VerifyWithLimits(bytes.NewReader(bts), format, Limits{
+				MaxBytes:   8 * 1024 * 1024,
+				MaxEntries: 4,
+			}))
+
+			err = VerifyWithLimits(bytes.NewReader(bts), format, Limits{MaxBytes: 1024 * 1024})
+			require.ErrorIs(t, err, ErrLimitExceeded)
+			require.ErrorContains(t, err, "more than 1048576 bytes")
+
+			if format != "gz" {
+				err = VerifyWithLimits(bytes.NewReader(bts), format, Limits{MaxEntries: 3})
+				require.ErrorIs(t, err, ErrLimitExceeded)
+				require.ErrorContains(t, err, "more than 3 entries")
+			}
+		})
+	}
+	t.Run("cpio name too long", func(t *testing.T) {
+		// a single header claiming a name of 4 GiB.
+		header := "070701" + strings.Repeat("00000000", 11) + "ffffffff" + "00000000"
+		err := VerifyWithLimits(strings.NewReader(header), "cpio", Limits{MaxBytes: 1024})
+		require.EqualError(t, err, "cpio: name too long: 4294967295 bytes")
+	})
+}
@@ -0,0 +1,155 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+)
+
+// NewMultiVolume creates an archive in the given format which is split into
+// volumes of at most maxBytes each, named prefix.part001, prefix.part002, and
+// so on.
+// If there are more than 999 volumes, they are renamed once the archive is
+// closed, so their numbers all have the same number of digits.
+//
+// When the archive is closed successfully, a prefix.manifest file is also
+// written, listing the SHA256 checksum of each volume, in order, in the same
+// format used by sha256sum.
+// The original archive can be reassembled by concatenating all the volumes,
+// e.g.: cat prefix.part* > prefix.
+//
+// With [WithStats], it implements [StatsReporter] too.
+func NewMultiVolume(prefix, format string, maxBytes int64, opts ...Option) (Archive, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("invalid volume size: %d", maxBytes)
+	}
+	v := &volumes{
+		prefix: prefix,
+		max:    maxBytes,
+	}
+	a, err := New(v, format, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return forwardStats(multiVolume{
+		Archive: a,
+		volumes: v,
+	}, a), nil
+}
+
+type multiVolume struct {
+	Archive
+	volumes *volumes
+}
+
+func (m multiVolume) unwrap() Archive { return m.Archive }
+
+func (m multiVolume) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	return addReader(m.Archive, f, info, r)
+}
+
+// Close closes the archive and its volumes, and writes the manifest, unless
+// closing the archive fails.
+func (m multiVolume) Close() error {
+	if err := m.Archive.Close(); err != nil {
+		return errors.Join(err, m.volumes.closeCurrent())
+	}
+	return m.volumes.Close()
+}
+
+// minVolumeDigits is the number of digits of volume numbers, unless there are
+// more volumes than that.
+const minVolumeDigits = 3
+
+// volumes is an io.WriteCloser which splits everything written to it into
+// files of at most max bytes.
+type volumes struct {
+	prefix  string
+	max     int64
+	current *os.File
+	written int64
+	hash    hash.Hash
+	sums    []string
+}
+
+// name returns the name of the given volume, numbered from 1, with at least
+// the given number of digits.
+func (v *volumes) name(n, digits int) string {
+	return fmt.Sprintf("%s.part%0*d", v.prefix, digits, n)
+}
+
+func (v *volumes) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		if v.current == nil || v.written == v.max {
+			if err := v.next(); err != nil {
+				return n, err
+			}
+		}
+		chunk := p[:min(int64(len(p)), v.max-v.written)]
+		w, err := v.current.Write(chunk)
+		v.hash.Write(chunk[:w])
+		v.written += int64(w)
+		n += w
+		if err != nil {
+			return n, err
+		}
+		p = p[w:]
+	}
+	return n, nil
+}
+
+// next closes the current volume, if any, and opens the next one.
+func (v *volumes) next() error {
+	if err := v.closeCurrent(); err != nil {
+		return err
+	}
+	f, err := os.Create(v.name(len(v.sums)+1, minVolumeDigits))
+	if err != nil {
+		return err
+	}
+	v.current = f
+	v.written = 0
+	v.hash = sha256.New()
+	return nil
+}
+
+func (v *volumes) closeCurrent() error {
+	if v.current == nil {
+		return nil
+	}
+	v.sums = append(v.sums, hex.EncodeToString(v.hash.Sum(nil)))
+	err := v.current.Close()
+	v.current = nil
+	return err
+}
+
+// Close closes the current volume, renames all of them if needed, and writes
+// the manifest.
+func (v *volumes) Close() error {
+	if err := v.closeCurrent(); err != nil {
+		return err
+	}
+	digits := max(minVolumeDigits, len(strconv.Itoa(len(v.sums))))
+	var manifest strings.Builder
+	for i, sum := range v.sums {
+		name := v.name(i+1, digits)
+		if digits > minVolumeDigits {
+			if err := os.Rename(v.name(i+1, minVolumeDigits), name); err != nil {
+				return err
+			}
+		}
+		manifest.WriteString(sum + "  " + filepath.Base(name) + "\n")
+	}
+	return os.WriteFile(v.prefix+".manifest", []byte(manifest.String()), 0o644)
+}
@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiVolume(t *testing.T) {
+	dir := t.TempDir()
+	content := make([]byte, 16*1024)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(content)
+	src := filepath.Join(dir, "random.bin")
+	require.NoError(t, os.WriteFile(src, content, 0o644))
+	files := []config.File{
+		{Source: src, Destination: "random.bin"},
+		{Source: "testdata/foo.txt", Destination: "foo.txt"},
+	}
+
+	var want bytes.Buffer
+	single, err := New(&want, "tar.gz")
+	require.NoError(t, err)
+	for _, f := range files {
+		require.NoError(t, single.Add(f))
+	}
+	require.NoError(t, single.Close())
+
+	prefix := filepath.Join(dir, "archive.tar.gz")
+	a, err := NewMultiVolume(prefix, "tar.gz", 4096)
+	require.NoError(t, err)
+	for _, f := range files {
+		require.NoError(t, a.Add(f))
+	}
+	require.NoError(t, a.Close())
+
+	parts, err := filepath.Glob(prefix + ".part*")
+	require.NoError(t, err)
+	require.Len(t, parts, 5)
+
+	var got bytes.Buffer
+	var manifest []string
+	for i, part := range parts {
+		require.Equal(t, fmt.Sprintf("%s.part%03d", prefix, i+1), part)
+		bts, err := os.ReadFile(part)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(bts), 4096)
+		got.Write(bts)
+		manifest = append(manifest, fmt.Sprintf("%x  %s", sha256.Sum256(bts), filepath.Base(part)))
+	}
+	require.Equal(t, want.Bytes(), got.Bytes())
+
+	bts, err := os.ReadFile(prefix + ".manifest")
+	require.NoError(t, err)
+	require.Equal(t, strings.Join(manifest, "\n")+"\n", string(bts))
+
+	t.Run("invalid size", func(t *testing.T) {
+		_, err := NewMultiVolume(prefix, "tar.gz", 0)
+		require.EqualError(t, err, "invalid volume size: 0")
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		_, err := NewMultiVolume(prefix, "7z", 1024)
+		require.EqualError(t, err, "invalid archive format: 7z")
+	})
+}
+
+func TestMultiVolumeFormat(t *testing.T) {
+	archive, err := NewMultiVolume(filepath.Join(t.TempDir(), "archive.zip"), "zip", 1024)
+	require.NoError(t, err)
+	require.Equal(t, "zip", archive.Format())
+	require.NoError(t, archive.Close())
+}
+
+func TestMultiVolumeManyVolumes(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "archive.tar")
+	a, err := NewMultiVolume(prefix, "tar", 2)
+	require.NoError(t, err)
+	require.NoError(t, a.Add(config.File{Source: "testdata/foo.txt", Destination: "foo.txt"}))
+	require.NoError(t, a.Close())
+
+	parts, err := filepath.Glob(prefix + ".part*")
+	require.NoError(t, err)
+	require.Len(t, parts, 1024)
+	require.Equal(t, prefix+".part0001", parts[0])
+	require.Equal(t, prefix+".part1024", parts[1023])
+
+	bts, err := os.ReadFile(prefix + ".manifest")
+	require.NoError(t, err)
+	manifest := strings.Split(strings.TrimSpace(string(bts)), "\n")
+	require.Len(t, manifest, 1024)
+	require.True(t, strings.HasSuffix(manifest[0], "  archive.tar.part0001"))
+}
+
+func TestMultiVolumeCloseError(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "foo.txt")
+	require.NoError(t, os.WriteFile(src, []byte("foo"), 0o644))
+
+	prefix := filepath.Join(dir, "archive.zip")
+	a, err := NewMultiVolume(prefix, "zip", 1024, WithReproducible())
+	require.NoError(t, err)
+	require.NoError(t, a.Add(config.File{Source: src, Destination: "foo.txt"}))
+	require.NoError(t, os.Remove(src))
+	require.ErrorIs(t, a.Close(), fs.ErrNotExist)
+	require.NoFileExists(t, prefix+".manifest")
+}
@@ -0,0 +1,58 @@
+package zip
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+)
+
+// sampleSize is how much of each file is compressed to estimate how
+// compressible it is, in adaptive archives.
+const sampleSize = 64 * 1024
+
+// NewAdaptive creates a zip archive which chooses whether to compress each
+// file by compressing a sample of its first bytes: files which barely shrink,
+// such as already compressed assets, are stored instead of deflated, saving
+// the time it would take to compress them.
+//
+// Files with an explicit compression method, and the ones which are always
+// stored because of their extension, are not sampled.
+func NewAdaptive(target io.Writer, opts ...Option) Archive {
+	a := New(target, opts...)
+	a.adaptive = true
+	return a
+}
+
+// adapt sets the compression method of the given header according to how
+// compressible the content of r seems to be, returning a reader with the
+// whole content of r, including the sampled part.
+func (a Archive) adapt(header *zip.FileHeader, method string, r io.Reader) (io.Reader, error) {
+	if !a.adaptive || method != "" || header.Method != zip.Deflate {
+		return r, nil
+	}
+	sample := make([]byte, sampleSize)
+	n, err := io.ReadFull(r, sample)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	sample = sample[:n]
+	if !compressible(sample) {
+		header.Method = zip.Store
+	}
+	return io.MultiReader(bytes.NewReader(sample), r), nil
+}
+
+// compressible tells whether the given sample shrinks by at least 10% when
+// compressed.
+func compressible(sample []byte) bool {
+	if len(sample) == 0 {
+		return true
+	}
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestSpeed)
+	_, _ = w.Write(sample)
+	_ = w.Close()
+	return buf.Len() < len(sample)*9/10
+}
@@ -0,0 +1,99 @@
+package zip
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZipAdaptive(t *testing.T) {
+	random := make([]byte, 200_000)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(random)
+	text := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog\n", 5000))
+
+	dir := t.TempDir()
+	for name, content := range map[string][]byte{
+		"random.bin": random,
+		"small.bin":  random[:100],
+		"text.txt":   text,
+		"empty.txt":  nil,
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o644))
+	}
+
+	build := func(tb testing.TB, newArchive func(io.Writer, ...Option) Archive) map[string]uint16 {
+		tb.Helper()
+		var buf bytes.Buffer
+		archive := newArchive(&buf)
+		for _, name := range []string{"random.bin", "small.bin", "text.txt", "empty.txt"} {
+			require.NoError(tb, archive.Add(config.File{
+				Source:      filepath.Join(dir, name),
+				Destination: name,
+			}))
+		}
+		require.NoError(tb, archive.Add(config.File{
+			Source:      filepath.Join(dir, "random.bin"),
+			Destination: "forced.bin",
+			Zip:         config.ZipFileInfo{CompressionMethod: "deflate"},
+		}))
+		require.NoError(tb, addMapFile(tb, archive, "fs/random.bin", &fstest.MapFile{Data: random, Mode: 0o644}))
+		require.NoError(tb, addMapFile(tb, archive, "fs/text.txt", &fstest.MapFile{Data: text, Mode: 0o644}))
+		require.NoError(tb, archive.Close())
+
+		r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(tb, err)
+		methods := map[string]uint16{}
+		for _, zf := range r.File {
+			methods[zf.Name] = zf.Method
+			rc, err := zf.Open()
+			require.NoError(tb, err)
+			bts, err := io.ReadAll(rc)
+			require.NoError(tb, err)
+			require.NoError(tb, rc.Close())
+			switch zf.Name {
+			case "text.txt", "fs/text.txt":
+				require.Equal(tb, text, bts)
+			case "empty.txt":
+				require.Empty(tb, bts)
+			case "small.bin":
+				require.Equal(tb, random[:100], bts)
+			default:
+				require.Equal(tb, random, bts, zf.Name)
+			}
+		}
+		return methods
+	}
+
+	t.Run("adaptive", func(t *testing.T) {
+		require.Equal(t, map[string]uint16{
+			"random.bin":    zip.Store,
+			"small.bin":     zip.Store,
+			"text.txt":      zip.Deflate,
+			"empty.txt":     zip.Deflate,
+			"forced.bin":    zip.Deflate,
+			"fs/random.bin": zip.Store,
+			"fs/text.txt":   zip.Deflate,
+		}, build(t, NewAdaptive))
+	})
+
+	t.Run("default", func(t *testing.T) {
+		require.Equal(t, map[string]uint16{
+			"random.bin":    zip.Deflate,
+			"small.bin":     zip.Deflate,
+			"text.txt":      zip.Deflate,
+			"empty.txt":     zip.Deflate,
+			"forced.bin":    zip.Deflate,
+			"fs/random.bin": zip.Deflate,
+			"fs/text.txt":   zip.Deflate,
+		}, build(t, New))
+	})
+}
@@ -0,0 +1,290 @@
+package zip
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1" // #nosec
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// WinZip AES constants, see https://www.winzip.com/en/support/aes-encryption/.
+const (
+	aesMethod     = 99
+	aesExtraID    = 0x9901
+	aesVersion    = 1 // AE-1, which keeps the CRC32 of the entries, see [NewEncrypted].
+	aesStrength   = 3 // AES-256.
+	aesKeySize    = 32
+	aesSaltSize   = 16
+	aesVerifySize = 2
+	aesMACSize    = 10
+	aesIterations = 1000
+)
+
+// ErrPassword is returned when opening an encrypted entry with the wrong
+// password.
+var ErrPassword = errors.New("zip: invalid password")
+
+// NewEncrypted creates a zip archive whose entries are encrypted with WinZip
+// AES-256, using the given password.
+//
+// Each entry is encrypted with its own random salt, after being compressed,
+// so encrypted archives are never byte for byte reproducible, even with
+// [WithReproducible].
+// Names, directories, and the central directory are not encrypted.
+//
+// Entries are encrypted as AE-1, which keeps the CRC32 of their unencrypted
+// content, as archive/zip always writes the checksum of streamed entries.
+// WinZip recommends AE-2, which leaves it out, for entries smaller than 20
+// bytes, as their checksum might give their content away, so such entries
+// should not hold secrets.
+func NewEncrypted(target io.Writer, password string, opts ...Option) Archive {
+	a := New(target, opts...)
+	e := &encryption{password: password}
+	a.z.RegisterCompressor(aesMethod, func(out io.Writer) (io.WriteCloser, error) {
+		return e.newWriter(out, a.level)
+	})
+	a.encryption = e
+	return a
+}
+
+// encryption holds the password of an encrypted archive, and the actual
+// compression method of the entry being created.
+type encryption struct {
+	password string
+	method   uint16
+}
+
+// encrypt makes the given header be of an encrypted entry, recording its
+// actual compression method in the AES extra field.
+func (e *encryption) encrypt(header *zip.FileHeader) {
+	if strings.HasSuffix(header.Name, "/") {
+		// directories have no data to encrypt.
+		return
+	}
+	extra := make([]byte, 11)
+	binary.LittleEndian.PutUint16(extra[0:], aesExtraID)
+	binary.LittleEndian.PutUint16(extra[2:], 7)
+	binary.LittleEndian.PutUint16(extra[4:], aesVersion)
+	copy(extra[6:], "AE")
+	extra[8] = aesStrength
+	binary.LittleEndian.PutUint16(extra[9:], header.Method)
+	header.Extra = append(header.Extra, extra...)
+	header.Flags |= 0x1
+	e.method = header.Method
+	header.Method = aesMethod
+}
+
+func (e *encryption) newWriter(out io.Writer, level int) (io.WriteCloser, error) {
+	salt := make([]byte, aesSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	stream, mac, verifier, err := aesKeys(e.password, salt)
+	if err != nil {
+		return nil, err
+	}
+	w := &aesWriter{
+		out:    out,
+		mac:    mac,
+		prefix: append(salt, verifier...),
+		enc:    &cipher.StreamWriter{S: stream, W: io.MultiWriter(out, mac)},
+	}
+	w.content = w.enc
+	if e.method == zip.Deflate {
+		fw, err := flate.NewWriter(w.enc, level)
+		if err != nil {
+			return nil, err
+		}
+		w.content, w.compressor = fw, fw
+	}
+	return w, nil
+}
+
+// aesWriter compresses and encrypts the content of an entry, writing the
+// authentication code of the encrypted data once closed.
+//
+// The salt and password verifier are only written along with the content, as
+// the compressor is created before the local header is written.
+type aesWriter struct {
+	out        io.Writer
+	prefix     []byte
+	mac        hash.Hash
+	enc        io.Writer
+	content    io.Writer
+	compressor io.WriteCloser
+}
+
+func (w *aesWriter) Write(p []byte) (int, error) {
+	if err := w.writePrefix(); err != nil {
+		return 0, err
+	}
+	return w.content.Write(p)
+}
+
+func (w *aesWriter) Close() error {
+	if err := w.writePrefix(); err != nil {
+		return err
+	}
+	if w.compressor != nil {
+		if err := w.compressor.Close(); err != nil {
+			return err
+		}
+	}
+	_, err := w.out.Write(w.mac.Sum(nil)[:aesMACSize])
+	return err
+}
+
+func (w *aesWriter) writePrefix() error {
+	if w.prefix == nil {
+		return nil
+	}
+	_, err := w.out.Write(w.prefix)
+	w.prefix = nil
+	return err
+}
+
+// OpenEncrypted returns a reader with the decrypted and decompressed content
+// of an entry of an archive created by [NewEncrypted].
+//
+// A wrong password fails with [ErrPassword] right away, while corrupted data
+// fails once the content is fully read.
+func OpenEncrypted(f *zip.File, password string) (io.ReadCloser, error) {
+	if f.Method != aesMethod {
+		return nil, fmt.Errorf("%s: entry is not encrypted", f.Name)
+	}
+	method, err := aesExtra(f.Extra)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	size := int64(f.CompressedSize64) - aesSaltSize - aesVerifySize - aesMACSize // #nosec
+	if size < 0 {
+		return nil, fmt.Errorf("%s: %w", f.Name, zip.ErrFormat)
+	}
+	raw, err := f.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, aesSaltSize+aesVerifySize)
+	if _, err := io.ReadFull(raw, prefix); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	stream, mac, verifier, err := aesKeys(password, prefix[:aesSaltSize])
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(verifier, prefix[aesSaltSize:]) {
+		return nil, fmt.Errorf("%s: %w", f.Name, ErrPassword)
+	}
+	var r io.Reader = &cipher.StreamReader{
+		S: stream,
+		R: &aesReader{r: raw, n: size, mac: mac},
+	}
+	switch method {
+	case zip.Store:
+		return io.NopCloser(r), nil
+	case zip.Deflate:
+		return flate.NewReader(r), nil
+	}
+	return nil, fmt.Errorf("%s: %w", f.Name, zip.ErrAlgorithm)
+}
+
+// aesReader reads the encrypted data of an entry, checking its
+// authentication code once it reaches the end.
+type aesReader struct {
+	r   io.Reader
+	n   int64
+	mac hash.Hash
+}
+
+func (r *aesReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		code := make([]byte, aesMACSize)
+		if _, err := io.ReadFull(r.r, code); err != nil {
+			return 0, err
+		}
+		if !hmac.Equal(code, r.mac.Sum(nil)[:aesMACSize]) {
+			return 0, zip.ErrChecksum
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.n {
+		p = p[:r.n]
+	}
+	n, err := r.r.Read(p)
+	r.n -= int64(n)
+	_, _ = r.mac.Write(p[:n])
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// aesExtra returns the actual compression method recorded in the AES extra
+// field.
+func aesExtra(extra []byte) (uint16, error) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		extra = extra[4:]
+		if size > len(extra) {
+			break
+		}
+		if id == aesExtraID && size == 7 {
+			if extra[4] != aesStrength {
+				return 0, fmt.Errorf("unsupported AES strength: %d", extra[4])
+			}
+			return binary.LittleEndian.Uint16(extra[5:]), nil
+		}
+		extra = extra[size:]
+	}
+	return 0, fmt.Errorf("missing AES extra field: %w", zip.ErrFormat)
+}
+
+// aesKeys derives the encryption and authentication keys, and the password
+// verifier, from the given password and salt.
+func aesKeys(password string, salt []byte) (cipher.Stream, hash.Hash, []byte, error) {
+	key, err := pbkdf2.Key(sha1.New, password, salt, aesIterations, 2*aesKeySize+aesVerifySize)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	block, err := aes.NewCipher(key[:aesKeySize])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	stream := &aesCTR{block: block}
+	mac := hmac.New(sha1.New, key[aesKeySize:2*aesKeySize])
+	return stream, mac, key[2*aesKeySize:], nil
+}
+
+// aesCTR is AES in counter mode as WinZip does it: with a little endian
+// counter starting at 1, which [cipher.NewCTR] doesn't support.
+type aesCTR struct {
+	block   cipher.Block
+	counter uint64
+	stream  [aes.BlockSize]byte
+	used    int
+}
+
+func (c *aesCTR) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if c.used == 0 || c.used == aes.BlockSize {
+			c.counter++
+			var nonce [aes.BlockSize]byte
+			binary.LittleEndian.PutUint64(nonce[:], c.counter)
+			c.block.Encrypt(c.stream[:], nonce[:])
+			c.used = 0
+		}
+		dst[i] = src[i] ^ c.stream[c.used]
+		c.used++
+	}
+}
@@ -0,0 +1,176 @@
+package zip
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZipEncrypted(t *testing.T) {
+	content := strings.Repeat("some very secret content\n", 1000)
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "secret.txt")
+	require.NoError(t, os.WriteFile(src, []byte(content), 0o644))
+	empty := filepath.Join(tmp, "empty.txt")
+	require.NoError(t, os.WriteFile(empty, nil, 0o644))
+
+	var buf bytes.Buffer
+	archive := NewEncrypted(&buf, "s3cr3t")
+	require.NoError(t, archive.Add(config.File{Source: src, Destination: "deflated.txt"}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      src,
+		Destination: "stored.txt",
+		Zip:         config.ZipFileInfo{CompressionMethod: "store"},
+	}))
+	require.NoError(t, archive.Add(config.File{Source: empty, Destination: "empty.txt"}))
+	require.NoError(t, archive.Add(config.File{
+		Destination: "dir",
+		Info:        config.FileInfo{Mode: fs.ModeDir | 0o755},
+	}))
+	require.NoError(t, archive.Close())
+	require.NotContains(t, buf.String(), "secret content")
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, r.File, 4)
+
+	expected := map[string]string{
+		"deflated.txt": content,
+		"stored.txt":   content,
+		"empty.txt":    "",
+	}
+	for _, f := range r.File {
+		if f.Mode().IsDir() {
+			require.Equal(t, "dir/", f.Name)
+			continue
+		}
+		t.Run(f.Name, func(t *testing.T) {
+			_, err := f.Open()
+			require.ErrorIs(t, err, zip.ErrAlgorithm)
+
+			rc, err := OpenEncrypted(f, "s3cr3t")
+			require.NoError(t, err)
+			bts, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+			require.Equal(t, expected[f.Name], string(bts))
+
+			_, err = OpenEncrypted(f, "wrong")
+			require.ErrorIs(t, err, ErrPassword)
+		})
+	}
+	require.Less(t, r.File[0].CompressedSize64, r.File[1].CompressedSize64)
+}
+
+func TestZipEncryptedCorrupted(t *testing.T) {
+	var buf bytes.Buffer
+	archive := NewEncrypted(&buf, "s3cr3t")
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+		Zip:         config.ZipFileInfo{CompressionMethod: "store"},
+	}))
+	require.NoError(t, archive.Close())
+
+	bts := buf.Bytes()
+	r, err := zip.NewReader(bytes.NewReader(bts), int64(len(bts)))
+	require.NoError(t, err)
+	offset, err := r.File[0].DataOffset()
+	require.NoError(t, err)
+	// first byte of the encrypted content, after the salt and verifier.
+	bts[offset+aesSaltSize+aesVerifySize] ^= 0xff
+
+	rc, err := OpenEncrypted(r.File[0], "s3cr3t")
+	require.NoError(t, err)
+	_, err = io.ReadAll(rc)
+	require.ErrorIs(t, err, zip.ErrChecksum)
+}
+
+func TestZipEncryptedNotEncrypted(t *testing.T) {
+	var buf bytes.Buffer
+	archive := New(&buf)
+	require.NoError(t, archive.Add(config.File{Source: "../testdata/foo.txt", Destination: "foo.txt"}))
+	require.NoError(t, archive.Close())
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	_, err = OpenEncrypted(r.File[0], "s3cr3t")
+	require.EqualError(t, err, "foo.txt: entry is not encrypted")
+}
+
+// TestZipEncryptedKnownVector checks the key derivation, counter mode and
+// authentication code against values computed with Python's hashlib and
+// OpenSSL's AES-256.
+func TestZipEncryptedKnownVector(t *testing.T) {
+	salt := make([]byte, aesSaltSize)
+	for i := range salt {
+		salt[i] = byte(i)
+	}
+	stream, mac, verifier, err := aesKeys("s3cr3t", salt)
+	require.NoError(t, err)
+	require.Equal(t, "ae7e", hex.EncodeToString(verifier))
+
+	plain := []byte("winzip aes interop!!")
+	encrypted := make([]byte, len(plain))
+	stream.XORKeyStream(encrypted, plain)
+	require.Equal(t, "4d3a7b0125448517b378007a42b88b8e48b3c146", hex.EncodeToString(encrypted))
+
+	mac.Write(encrypted)
+	require.Equal(t, "73ed1f085b1e60153949", hex.EncodeToString(mac.Sum(nil)[:aesMACSize]))
+}
+
+func TestZipEncryptedBsdtar(t *testing.T) {
+	if _, err := exec.LookPath("bsdtar"); err != nil {
+		t.Skip("bsdtar not available")
+	}
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "test.zip")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	archive := NewEncrypted(f, "s3cr3t")
+	require.NoError(t, archive.Add(config.File{Source: "../testdata/foo.txt", Destination: "deflated.txt"}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "stored.txt",
+		Zip:         config.ZipFileInfo{CompressionMethod: "store"},
+	}))
+	require.NoError(t, archive.Close())
+	require.NoError(t, f.Close())
+
+	out := filepath.Join(tmp, "out")
+	require.NoError(t, os.Mkdir(out, 0o755))
+	bts, err := exec.Command("bsdtar", "-x", "--passphrase", "s3cr3t", "-f", path, "-C", out).CombinedOutput()
+	require.NoError(t, err, string(bts))
+	for _, name := range []string{"deflated.txt", "stored.txt"} {
+		bts, err := os.ReadFile(filepath.Join(out, name))
+		require.NoError(t, err)
+		require.Equal(t, "foo\n", string(bts))
+	}
+
+	bts, err = exec.Command("bsdtar", "-x", "--passphrase", "wrong", "-f", path, "-C", out).CombinedOutput()
+	require.Error(t, err)
+	require.Contains(t, string(bts), "Incorrect passphrase")
+}
+
+func TestZipEncryptedNotReproducible(t *testing.T) {
+	create := func(tb testing.TB) []byte {
+		tb.Helper()
+		var buf bytes.Buffer
+		archive := NewEncrypted(&buf, "s3cr3t", WithReproducible())
+		require.NoError(tb, archive.Add(config.File{Source: "../testdata/foo.txt", Destination: "foo.txt"}))
+		require.NoError(tb, archive.Close())
+		return buf.Bytes()
+	}
+	// each entry is encrypted with a random salt.
+	require.NotEqual(t, create(t), create(t))
+}
@@ -0,0 +1,204 @@
+package zip
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// eocdSignature is the signature of the end of central directory record.
+	eocdSignature = "PK\x05\x06"
+
+	// eocdLen is the length of the end of central directory record, without
+	// its comment.
+	eocdLen = 22
+)
+
+// OpenForAppend opens the zip archive in the given file, which must be open
+// for reading and writing, so new entries can be added to it.
+//
+// New entries are written to a temporary file, and only copied over the
+// existing central directory on Close, followed by the existing and new
+// central directories, so the existing entries are left untouched.
+// Until then, or if anything goes wrong, the file is left as it was.
+// The existing archive comment is kept, unless a new one is set with
+// [WithComment].
+// The file itself is not closed.
+//
+// Zip64 archives are not supported.
+func OpenForAppend(f *os.File, opts ...Option) (Archive, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return Archive{}, err
+	}
+	r, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return Archive{}, err
+	}
+	eocd, err := readEOCD(f, info.Size())
+	if err != nil {
+		return Archive{}, fmt.Errorf("%s: %w", f.Name(), err)
+	}
+	tail := make([]byte, info.Size()-eocd.offset)
+	if _, err := f.ReadAt(tail, eocd.offset); err != nil {
+		return Archive{}, fmt.Errorf("%s: %w", f.Name(), err)
+	}
+	if int64(len(tail)) < eocd.size {
+		return Archive{}, fmt.Errorf("%s: %w", f.Name(), zip.ErrFormat)
+	}
+	tmp, err := os.CreateTemp("", "goreleaser-append-*")
+	if err != nil {
+		return Archive{}, err
+	}
+
+	a := New(tmp, opts...)
+	a.z.SetOffset(eocd.offset)
+	a.appender = &appender{
+		f:       f,
+		tmp:     tmp,
+		tail:    tail,
+		cd:      tail[:eocd.size],
+		offset:  eocd.offset,
+		entries: eocd.entries,
+		comment: eocd.comment,
+	}
+	if a.comment != "" {
+		a.appender.comment = []byte(a.comment)
+	}
+	for _, zf := range r.File {
+		name := strings.TrimSuffix(zf.Name, "/")
+		a.files[name] = true
+		if a.folded != nil {
+			a.folded[strings.ToLower(name)] = name
+		}
+	}
+	return a, nil
+}
+
+// appender holds the state of an archive opened by [OpenForAppend].
+type appender struct {
+	f   *os.File
+	tmp *os.File
+	// tail is everything from the existing central directory to the end of
+	// the file, so it can be restored.
+	tail    []byte
+	cd      []byte
+	offset  int64
+	entries int
+	comment []byte
+}
+
+// close writes the central directory of the new entries to the temporary
+// file, and then copies the new entries over the existing central directory,
+// followed by the existing and new ones.
+func (ap *appender) close(z *zip.Writer) error {
+	defer ap.remove()
+	if err := z.Close(); err != nil {
+		return err
+	}
+	end, err := ap.tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	eocd, err := readEOCD(ap.tmp, end)
+	if err != nil {
+		return fmt.Errorf("%s: %w", ap.tmp.Name(), err)
+	}
+	// offsets are absolute, as the writer offset was set to where it started.
+	start := eocd.offset
+	added := make([]byte, eocd.size)
+	if _, err := ap.tmp.ReadAt(added, start-ap.offset); err != nil {
+		return fmt.Errorf("%s: %w", ap.tmp.Name(), err)
+	}
+
+	entries := ap.entries + eocd.entries
+	size := int64(len(ap.cd)) + eocd.size
+	if entries > 0xffff || size > 0xffffffff || start > 0xffffffff {
+		return fmt.Errorf("%s: %w", ap.f.Name(), errZip64)
+	}
+	var buf bytes.Buffer
+	buf.Write(ap.cd)
+	buf.Write(added)
+	buf.WriteString(eocdSignature)
+	_ = binary.Write(&buf, binary.LittleEndian, [4]uint16{0, 0, uint16(entries), uint16(entries)})
+	_ = binary.Write(&buf, binary.LittleEndian, [2]uint32{uint32(size), uint32(start)})
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(len(ap.comment)))
+	buf.Write(ap.comment)
+
+	if err := ap.write(start, buf.Bytes()); err != nil {
+		// puts the existing central directory back.
+		_, _ = ap.f.WriteAt(ap.tail, ap.offset)
+		_ = ap.f.Truncate(ap.offset + int64(len(ap.tail)))
+		return fmt.Errorf("%s: %w", ap.f.Name(), err)
+	}
+	return nil
+}
+
+// write copies the new entries from the temporary file to the archive, where
+// its central directory started, followed by the given central directories.
+func (ap *appender) write(start int64, cd []byte) error {
+	if _, err := io.Copy(
+		io.NewOffsetWriter(ap.f, ap.offset),
+		io.NewSectionReader(ap.tmp, 0, start-ap.offset),
+	); err != nil {
+		return err
+	}
+	if _, err := ap.f.WriteAt(cd, start); err != nil {
+		return err
+	}
+	return ap.f.Truncate(start + int64(len(cd)))
+}
+
+// remove closes and removes the temporary file.
+func (ap *appender) remove() {
+	_ = ap.tmp.Close()
+	_ = os.Remove(ap.tmp.Name())
+}
+
+var errZip64 = errors.New("zip64 archives can't be appended to")
+
+type eocd struct {
+	entries int
+	size    int64
+	offset  int64
+	comment []byte
+}
+
+// readEOCD reads the end of central directory record of the zip archive of
+// the given size.
+func readEOCD(r io.ReaderAt, size int64) (eocd, error) {
+	n := min(size, eocdLen+0xffff)
+	buf := make([]byte, n)
+	if _, err := r.ReadAt(buf, size-n); err != nil {
+		return eocd{}, err
+	}
+	for i := len(buf) - eocdLen; i >= 0; i-- {
+		if string(buf[i:i+4]) != eocdSignature {
+			continue
+		}
+		rec := buf[i:]
+		commentLen := int(binary.LittleEndian.Uint16(rec[20:]))
+		if eocdLen+commentLen != len(rec) {
+			continue
+		}
+		entries := binary.LittleEndian.Uint16(rec[10:])
+		cdSize := binary.LittleEndian.Uint32(rec[12:])
+		cdOffset := binary.LittleEndian.Uint32(rec[16:])
+		if entries == 0xffff || cdSize == 0xffffffff || cdOffset == 0xffffffff {
+			return eocd{}, errZip64
+		}
+		return eocd{
+			entries: int(entries),
+			size:    int64(cdSize),
+			offset:  int64(cdOffset),
+			comment: bytes.Clone(rec[eocdLen:]),
+		}, nil
+	}
+	return eocd{}, zip.ErrFormat
+}
@@ -0,0 +1,166 @@
+package zip
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenForAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.zip")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	require.NoError(t, zw.SetComment("prebuilt"))
+	for name, content := range map[string]string{
+		"a.txt":     "a",
+		"sub/b.txt": "b",
+	} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = io.WriteString(w, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	before, err := os.ReadFile(path)
+	require.NoError(t, err)
+	eocd, err := readEOCD(bytes.NewReader(before), int64(len(before)))
+	require.NoError(t, err)
+
+	f, err = os.OpenFile(path, os.O_RDWR, 0)
+	require.NoError(t, err)
+	defer f.Close()
+	archive, err := OpenForAppend(f)
+	require.NoError(t, err)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/sub1/bar.txt",
+		Destination: "sub/bar.txt",
+	}))
+	require.ErrorIs(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "a.txt",
+	}), fs.ErrExist)
+	require.NoError(t, archive.Close())
+	require.NoError(t, f.Close())
+
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	// the existing entries are left untouched.
+	require.Equal(t, before[:eocd.offset], after[:eocd.offset])
+
+	r, err := zip.NewReader(bytes.NewReader(after), int64(len(after)))
+	require.NoError(t, err)
+	require.Equal(t, "prebuilt", r.Comment)
+	contents := map[string]string{}
+	for _, zf := range r.File {
+		rc, err := zf.Open()
+		require.NoError(t, err)
+		bts, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		contents[zf.Name] = string(bts)
+	}
+	require.Equal(t, map[string]string{
+		"a.txt":       "a",
+		"sub/b.txt":   "b",
+		"foo.txt":     "foo\n",
+		"sub/bar.txt": "bar\n",
+	}, contents)
+
+	if _, err := exec.LookPath("unzip"); err == nil {
+		out, err := exec.Command("unzip", "-t", path).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+}
+
+func TestOpenForAppendInvalid(t *testing.T) {
+	f, err := os.Open("../testdata/foo.txt")
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = OpenForAppend(f)
+	require.ErrorIs(t, err, zip.ErrFormat)
+}
+
+func TestOpenForAppendComment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.zip")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	archive := New(f, WithComment("old"))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	require.NoError(t, archive.Close())
+	require.NoError(t, f.Close())
+
+	f, err = os.OpenFile(path, os.O_RDWR, 0)
+	require.NoError(t, err)
+	defer f.Close()
+	archive, err = OpenForAppend(f, WithComment("new"))
+	require.NoError(t, err)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/sub1/bar.txt",
+		Destination: "bar.txt",
+	}))
+	require.NoError(t, archive.Close())
+
+	r, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer r.Close()
+	require.Equal(t, "new", r.Comment)
+	require.Len(t, r.File, 2)
+}
+
+func TestOpenForAppendNotClosed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.zip")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	archive := New(f)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	require.NoError(t, archive.Close())
+	require.NoError(t, f.Close())
+	before, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	f, err = os.OpenFile(path, os.O_RDWR, 0)
+	require.NoError(t, err)
+	defer f.Close()
+	archive, err = OpenForAppend(f)
+	require.NoError(t, err)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/sub1/bar.txt",
+		Destination: "bar.txt",
+	}))
+	require.Error(t, archive.Add(config.File{
+		Source:      "../testdata/nope.txt",
+		Destination: "nope.txt",
+	}))
+
+	// nothing is written to the archive until it is closed.
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	require.NoError(t, archive.Close())
+	r, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer r.Close()
+	require.Len(t, r.File, 2)
+}
@@ -0,0 +1,19 @@
+package zip
+
+import "io"
+
+// NewAt creates a zip archive writing to w from the given offset, e.g. right
+// after an executable stub, to create self-extracting archives.
+//
+// The offsets in the central directory are relative to the start of w, so
+// the whole of it is a valid zip file, and the data before the offset is
+// left untouched.
+//
+// Zip archives are streamed: entries are written as they are added, followed
+// by data descriptors with their checksum and sizes, and the central
+// directory is only written on Close, so nothing is buffered nor patched.
+func NewAt(w io.WriterAt, offset int64, opts ...Option) Archive {
+	a := New(io.NewOffsetWriter(w, offset), opts...)
+	a.z.SetOffset(offset)
+	return a
+}
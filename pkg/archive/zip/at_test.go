@@ -0,0 +1,129 @@
+package zip
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goreleaser/goreleaser/v2/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZipNewAt(t *testing.T) {
+	dir := t.TempDir()
+	random := make([]byte, 5*1024*1024+3)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(random)
+	contents := map[string][]byte{
+		"random.bin": random,
+		"text.txt":   []byte(strings.Repeat("hello zip\n", 100_000)),
+		"empty.txt":  {},
+		"image.png":  random[:1000],
+	}
+	for name, content := range contents {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o644))
+	}
+
+	stub := []byte("#!/bin/sh\nexec unzip -o \"$0\"\n")
+	path := filepath.Join(dir, "test.zip")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	_, err = f.Write(stub)
+	require.NoError(t, err)
+	archive := NewAt(f, int64(len(stub)))
+	for _, name := range []string{"random.bin", "text.txt", "empty.txt", "image.png"} {
+		require.NoError(t, archive.Add(config.File{
+			Source:      filepath.Join(dir, name),
+			Destination: "files/" + name,
+		}))
+	}
+	require.NoError(t, archive.Add(config.File{
+		Destination: "empty-dir",
+		Info:        config.FileInfo{Mode: fs.ModeDir | 0o755},
+	}))
+	require.NoError(t, archive.Close())
+	require.NoError(t, f.Close())
+
+	bts, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, stub, bts[:len(stub)])
+
+	t.Run("read back", func(t *testing.T) {
+		r, err := zip.NewReader(bytes.NewReader(bts), int64(len(bts)))
+		require.NoError(t, err)
+		require.Len(t, r.File, 5)
+		for _, zf := range r.File {
+			if zf.Mode().IsDir() {
+				continue
+			}
+			rc, err := zf.Open()
+			require.NoError(t, err)
+			got, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+			require.Equal(t, contents[strings.TrimPrefix(zf.Name, "files/")], got, zf.Name)
+		}
+	})
+
+	t.Run("local headers", func(t *testing.T) {
+		r, err := zip.NewReader(bytes.NewReader(bts), int64(len(bts)))
+		require.NoError(t, err)
+		sizes := map[string]int{}
+		for _, zf := range r.File {
+			sizes[zf.Name] = int(zf.CompressedSize64)
+		}
+
+		// entries with data descriptors have no checksum nor sizes in their
+		// local headers.
+		var names []string
+		off := len(stub)
+		for string(bts[off:off+4]) == "PK\x03\x04" {
+			flags := binary.LittleEndian.Uint16(bts[off+6:])
+			crc := binary.LittleEndian.Uint32(bts[off+14:])
+			compressed := binary.LittleEndian.Uint32(bts[off+18:])
+			size := binary.LittleEndian.Uint32(bts[off+22:])
+			nameLen := int(binary.LittleEndian.Uint16(bts[off+26:]))
+			extraLen := int(binary.LittleEndian.Uint16(bts[off+28:]))
+			name := string(bts[off+30 : off+30+nameLen])
+			names = append(names, name)
+			off += 30 + nameLen + extraLen + sizes[name]
+
+			if content, ok := contents[strings.TrimPrefix(name, "files/")]; ok {
+				require.NotZero(t, flags&0x8, name)
+				require.Zero(t, crc, name)
+				require.Zero(t, compressed, name)
+				require.Zero(t, size, name)
+				require.Equal(t, "PK\x07\x08", string(bts[off:off+4]), name)
+				require.Equal(t, crc32.ChecksumIEEE(content), binary.LittleEndian.Uint32(bts[off+4:]), name)
+				off += 16
+			}
+		}
+		require.Equal(t, "PK\x01\x02", string(bts[off:off+4]))
+		// the first entry is right after the stub.
+		require.Equal(t, uint32(len(stub)), binary.LittleEndian.Uint32(bts[off+42:]))
+		require.Equal(t, []string{
+			"files/random.bin",
+			"files/text.txt",
+			"files/empty.txt",
+			"files/image.png",
+			"empty-dir/",
+		}, names)
+	})
+
+	t.Run("unzip", func(t *testing.T) {
+		if _, err := exec.LookPath("unzip"); err != nil {
+			t.Skip("unzip not available")
+		}
+		out, err := exec.Command("unzip", "-t", path).CombinedOutput()
+		require.NoError(t, err, string(out))
+		require.NotContains(t, string(out), "warning")
+	})
+}
@@ -5,34 +5,113 @@ package zip
 import (
 	"archive/zip"
 	"compress/flate"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/closed"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/copybuf"
+	"github.com/goreleaser/goreleaser/v2/pkg/archive/internal/destination"
 	"github.com/goreleaser/goreleaser/v2/pkg/config"
 )
 
 // Archive zip struct.
 type Archive struct {
-	z     *zip.Writer
-	files map[string]bool
+	z      *zip.Writer
+	files  map[string]bool
+	folded destination.Folded
+	sorted *sorted
+
+	appender   *appender
+	encryption *encryption
+	closed     *closed.Flag
+	afterClose *[]func()
+	bufSize    int
+	adaptive   bool
+	comment    string
+	level      int
+}
+
+// Option customizes the zip archive.
+type Option func(*Archive)
+
+// WithCaseInsensitiveCheck makes Add fail when the destination only differs
+// by case from a previously added one, as they would overwrite each other
+// when extracted in case-insensitive file systems (e.g. macOS and Windows).
+func WithCaseInsensitiveCheck() Option {
+	return func(a *Archive) {
+		a.folded = destination.Folded{}
+	}
+}
+
+// WithReproducible makes the archive reproducible, by writing its entries
+// sorted by name when it is closed, and normalizing their headers.
+//
+// Sources are only read when the archive is closed.
+// Encrypted archives, see [NewEncrypted], are still not reproducible, as their
+// entries are encrypted with random salts.
+func WithReproducible() Option {
+	return func(a *Archive) {
+		a.sorted = &sorted{}
+	}
+}
+
+// WithCopyBufferSize sets the size of the buffer used to copy the content of
+// files into the archive, which can speed up adding large files.
+// Zero keeps the default size, 32KB.
+func WithCopyBufferSize(size int) Option {
+	return func(a *Archive) {
+		a.bufSize = size
+	}
+}
+
+// WithComment sets the comment of the whole archive.
+// Comments of each entry are taken from their file info.
+func WithComment(comment string) Option {
+	return func(a *Archive) {
+		a.comment = comment
+	}
+}
+
+// WithLevel sets the compression level of deflated entries, from
+// [flate.HuffmanOnly] to [flate.BestCompression], which is the default.
+// Invalid levels make Add fail.
+func WithLevel(level int) Option {
+	return func(a *Archive) {
+		a.level = level
+	}
 }
 
 // New zip archive.
-func New(target io.Writer) Archive {
+func New(target io.Writer, opts ...Option) Archive {
 	compressor := zip.NewWriter(target)
+	a := Archive{
+		z:          compressor,
+		files:      map[string]bool{},
+		closed:     &closed.Flag{},
+		afterClose: &[]func(){},
+		level:      flate.BestCompression,
+	}
+	for _, opt := range opts {
+		opt(&a)
+	}
 	compressor.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
-		return flate.NewWriter(out, flate.BestCompression)
+		return flate.NewWriter(out, a.level)
 	})
-	return Archive{
-		z:     compressor,
-		files: map[string]bool{},
+	if a.comment != "" {
+		// only fails if the comment is too long, which is checked on Close.
+		_ = compressor.SetComment(a.comment)
 	}
+	return a
 }
 
-func Copy(source *os.File, target io.Writer) (Archive, error) {
+func Copy(source *os.File, target io.Writer, opts ...Option) (Archive, error) {
 	info, err := source.Stat()
 	if err != nil {
 		return Archive{}, err
@@ -41,9 +120,13 @@ func Copy(source *os.File, target io.Writer) (Archive, error) {
 	if err != nil {
 		return Archive{}, err
 	}
-	w := New(target)
+	w := New(target, opts...)
 	for _, zf := range r.File {
-		w.files[zf.Name] = true
+		name := strings.TrimSuffix(zf.Name, "/")
+		w.files[name] = true
+		if w.folded != nil {
+			w.folded[strings.ToLower(name)] = name
+		}
 		hdr := zip.FileHeader{
 			Name:               zf.Name,
 			UncompressedSize64: zf.UncompressedSize64,
@@ -71,17 +154,73 @@ func Copy(source *os.File, target io.Writer) (Archive, error) {
 	return w, nil
 }
 
+// Format returns the archive format, "zip".
+func (a Archive) Format() string {
+	return "zip"
+}
+
 // Close all closeables.
 func (a Archive) Close() error {
+	if err := a.closed.Close(); err != nil {
+		return err
+	}
+	defer func() {
+		for _, fn := range *a.afterClose {
+			fn()
+		}
+	}()
+	if a.sorted != nil {
+		if err := a.sorted.write(); err != nil {
+			if a.appender != nil {
+				a.appender.remove()
+			}
+			return err
+		}
+	}
+	if a.appender != nil {
+		return a.appender.close(a.z)
+	}
 	return a.z.Close()
 }
 
+// AfterClose makes the given function be called once the archive is closed,
+// even if closing it fails, e.g. to remove temporary sources, which
+// reproducible archives only read then.
+func (a Archive) AfterClose(fn func()) {
+	*a.afterClose = append(*a.afterClose, fn)
+}
+
 // Add a file to the zip archive.
 func (a Archive) Add(f config.File) error {
-	if _, ok := a.files[f.Destination]; ok {
-		return &fs.PathError{Err: fs.ErrExist, Path: f.Destination, Op: "add"}
+	// entry names always use forward slashes, even when built on Windows.
+	f.Destination = filepath.ToSlash(f.Destination)
+	if err := a.closed.Check(f.Destination); err != nil {
+		return err
+	}
+	if err := a.register(f.Destination); err != nil {
+		return err
+	}
+	if a.sorted == nil {
+		return a.add(f)
+	}
+	if _, err := compressionMethod(f); err != nil {
+		return err
+	}
+	if f.Source != "" || !f.Info.Mode.IsDir() {
+		if _, err := os.Lstat(f.Source); err != nil { // #nosec
+			return err
+		}
+	}
+	a.sorted.add(f.Destination, func() error {
+		return a.add(f)
+	})
+	return nil
+}
+
+func (a Archive) add(f config.File) error {
+	if f.Source == "" && f.Info.Mode.IsDir() {
+		return a.addDir(f)
 	}
-	a.files[f.Destination] = true
 	info, err := os.Lstat(f.Source) // #nosec
 	if err != nil {
 		return err
@@ -89,40 +228,242 @@ func (a Archive) Add(f config.File) error {
 	if info.IsDir() {
 		return err
 	}
-	header, err := zip.FileInfoHeader(info)
+	header, err := fileHeader(f, info)
 	if err != nil {
 		return err
 	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		w, err := a.createHeader(header)
+		if err != nil {
+			return err
+		}
+		link, err := os.Readlink(f.Source) // #nosec
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Source, err)
+		}
+		_, err = io.WriteString(w, filepath.ToSlash(link))
+		return err
+	}
+	file, err := os.Open(f.Source) // #nosec
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := a.write(header, f.Zip.CompressionMethod, file); err != nil {
+		return fmt.Errorf("%s: %w", f.Source, err)
+	}
+	return nil
+}
+
+// fileHeader creates the header of the given file, with the given info of
+// its content.
+func fileHeader(f config.File, info fs.FileInfo) (*zip.FileHeader, error) {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return nil, err
+	}
 	header.Name = f.Destination
-	header.Method = zip.Deflate
+	header.Method, err = compressionMethod(f)
+	if err != nil {
+		return nil, err
+	}
 	if !f.Info.ParsedMTime.IsZero() {
 		header.Modified = f.Info.ParsedMTime
 	}
 	if f.Info.Mode != 0 {
 		header.SetMode(f.Info.Mode)
 	}
-	w, err := a.z.CreateHeader(header)
+	header.Comment = f.Zip.Comment
+	return header, nil
+}
+
+// write adds an entry with the given header and the content read from r.
+func (a Archive) write(header *zip.FileHeader, method string, r io.Reader) error {
+	r, err := a.adapt(header, method, r)
 	if err != nil {
 		return err
 	}
-	if info.IsDir() {
-		return nil
+	w, err := a.createHeader(header)
+	if err != nil {
+		return err
 	}
-	if info.Mode()&os.ModeSymlink != 0 {
-		link, err := os.Readlink(f.Source) // #nosec
+	_, err = copybuf.Copy(w, r, a.bufSize)
+	return err
+}
+
+// AddWithReader adds a regular file to the archive, reading its content from
+// r, with the size, mode and modification time of the given info, unless set
+// in the file info.
+//
+// Reproducible archives copy the content into a temporary file, as they only
+// write their entries when closed.
+func (a Archive) AddWithReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	// entry names always use forward slashes, even when built on Windows.
+	f.Destination = filepath.ToSlash(f.Destination)
+	if err := a.closed.Check(f.Destination); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s: not a regular file: %s", f.Destination, info.Mode().Type())
+	}
+	if err := a.register(f.Destination); err != nil {
+		return err
+	}
+	if a.sorted == nil {
+		return a.addReader(f, info, r)
+	}
+	if _, err := compressionMethod(f); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp("", "goreleaser-zip-*")
+	if err != nil {
+		return err
+	}
+	a.AfterClose(func() { _ = os.Remove(tmp.Name()) })
+	_, err = copybuf.Copy(tmp, r, a.bufSize)
+	if err := errors.Join(err, tmp.Close()); err != nil {
+		return fmt.Errorf("%s: %w", f.Destination, err)
+	}
+	a.sorted.add(f.Destination, func() error {
+		file, err := os.Open(tmp.Name())
 		if err != nil {
-			return fmt.Errorf("%s: %w", f.Source, err)
+			return err
 		}
-		_, err = io.WriteString(w, filepath.ToSlash(link))
+		defer file.Close()
+		return a.addReader(f, info, file)
+	})
+	return nil
+}
+
+func (a Archive) addReader(f config.File, info fs.FileInfo, r io.Reader) error {
+	header, err := fileHeader(f, info)
+	if err != nil {
 		return err
 	}
-	file, err := os.Open(f.Source) // #nosec
-	if err != nil {
+	return a.write(header, f.Zip.CompressionMethod, r)
+}
+
+// createHeader adds the given header to the archive, normalizing it first if
+// the archive is reproducible, and making it encrypted if the archive is.
+func (a Archive) createHeader(header *zip.FileHeader) (io.Writer, error) {
+	if a.sorted != nil {
+		// the local time zone would leak into the MS-DOS timestamp.
+		header.Modified = header.Modified.UTC().Truncate(time.Second)
+		header.Extra = nil
+	}
+	if a.encryption != nil {
+		a.encryption.encrypt(header)
+	}
+	return a.z.CreateHeader(header)
+}
+
+// sorted holds the entries of a reproducible archive, which are only written
+// when it is closed.
+type sorted struct {
+	entries []sortedEntry
+}
+
+type sortedEntry struct {
+	name  string
+	write func() error
+}
+
+func (s *sorted) add(name string, write func() error) {
+	s.entries = append(s.entries, sortedEntry{name: name, write: write})
+}
+
+// write writes all the entries, sorted by name.
+func (s *sorted) write() error {
+	slices.SortFunc(s.entries, func(a, b sortedEntry) int {
+		return strings.Compare(a.name, b.name)
+	})
+	for _, e := range s.entries {
+		if err := e.write(); err != nil {
+			return err
+		}
+	}
+	s.entries = nil
+	return nil
+}
+
+// register validates the given destination, and marks it as added.
+func (a Archive) register(dst string) error {
+	if err := destination.Validate(dst); err != nil {
 		return err
 	}
-	defer file.Close()
-	_, err = io.Copy(w, file)
+	// directories might be added with or without a trailing slash.
+	dst = strings.TrimSuffix(dst, "/")
+	if _, ok := a.files[dst]; ok {
+		return &fs.PathError{Err: fs.ErrExist, Path: dst, Op: "add"}
+	}
+	if a.folded != nil {
+		if err := a.folded.Add(dst); err != nil {
+			return err
+		}
+	}
+	a.files[dst] = true
+	return nil
+}
+
+// addDir adds an explicit directory entry, which has no source in the disk.
+func (a Archive) addDir(f config.File) error {
+	header := &zip.FileHeader{
+		Name:     strings.TrimSuffix(f.Destination, "/") + "/",
+		Method:   zip.Store,
+		Modified: f.Info.ParsedMTime,
+		Comment:  f.Zip.Comment,
+	}
+	if header.Modified.IsZero() {
+		header.Modified = time.Now()
+	}
+	header.SetMode(f.Info.Mode)
+	_, err := a.createHeader(header)
 	return err
 }
 
-// TODO: test fileinfo stuff
+// incompressible holds the extensions of file types that are already
+// compressed, and thus are stored instead of deflated.
+var incompressible = map[string]bool{
+	".7z":    true,
+	".avi":   true,
+	".br":    true,
+	".bz2":   true,
+	".gif":   true,
+	".gz":    true,
+	".jar":   true,
+	".jpeg":  true,
+	".jpg":   true,
+	".mkv":   true,
+	".mov":   true,
+	".mp3":   true,
+	".mp4":   true,
+	".ogg":   true,
+	".png":   true,
+	".rar":   true,
+	".tgz":   true,
+	".txz":   true,
+	".tzst":  true,
+	".webm":  true,
+	".webp":  true,
+	".whl":   true,
+	".woff":  true,
+	".woff2": true,
+	".xz":    true,
+	".zip":   true,
+	".zst":   true,
+}
+
+func compressionMethod(f config.File) (uint16, error) {
+	switch f.Zip.CompressionMethod {
+	case "store":
+		return zip.Store, nil
+	case "deflate":
+		return zip.Deflate, nil
+	case "":
+		if incompressible[strings.ToLower(filepath.Ext(f.Destination))] {
+			return zip.Store, nil
+		}
+		return zip.Deflate, nil
+	}
+	return 0, fmt.Errorf("%s: invalid compression method: %s", f.Destination, f.Zip.CompressionMethod)
+}
@@ -5,9 +5,12 @@ import (
 	"bytes"
 	"io"
 	"io/fs"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/goreleaser/goreleaser/v2/internal/testlib"
@@ -167,3 +170,303 @@ func TestTarInvalidLink(t *testing.T) {
 }
 
 // TODO: add copying test
+
+func TestZipCompressionMethod(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.zip"))
+	require.NoError(t, err)
+	defer f.Close()
+	archive := New(f)
+	defer archive.Close()
+
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "image.PNG",
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "stored.txt",
+		Zip: config.ZipFileInfo{
+			CompressionMethod: "store",
+		},
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "deflated.png",
+		Zip: config.ZipFileInfo{
+			CompressionMethod: "deflate",
+		},
+	}))
+	require.EqualError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "invalid.txt",
+		Zip: config.ZipFileInfo{
+			CompressionMethod: "lzma",
+		},
+	}), "invalid.txt: invalid compression method: lzma")
+
+	require.NoError(t, archive.Close())
+	require.NoError(t, f.Close())
+
+	f, err = os.Open(f.Name())
+	require.NoError(t, err)
+	defer f.Close()
+
+	info, err := f.Stat()
+	require.NoError(t, err)
+
+	r, err := zip.NewReader(f, info.Size())
+	require.NoError(t, err)
+
+	methods := map[string]uint16{}
+	for _, zf := range r.File {
+		methods[zf.Name] = zf.Method
+	}
+	require.Equal(t, map[string]uint16{
+		"foo.txt":      zip.Deflate,
+		"image.PNG":    zip.Store,
+		"stored.txt":   zip.Store,
+		"deflated.png": zip.Deflate,
+	}, methods)
+}
+
+func TestZipEmptyDir(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.zip"))
+	require.NoError(t, err)
+	defer f.Close()
+	archive := New(f)
+	defer archive.Close()
+
+	require.NoError(t, archive.Add(config.File{
+		Destination: "logs/",
+		Info: config.FileInfo{
+			Mode: os.ModeDir | 0o750,
+		},
+	}))
+	require.ErrorIs(t, archive.Add(config.File{
+		Destination: "logs",
+		Info: config.FileInfo{
+			Mode: os.ModeDir | 0o750,
+		},
+	}), fs.ErrExist)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "foo.txt",
+	}))
+	require.NoError(t, archive.Close())
+	require.NoError(t, f.Close())
+
+	f, err = os.Open(f.Name())
+	require.NoError(t, err)
+	defer f.Close()
+
+	info, err := f.Stat()
+	require.NoError(t, err)
+
+	r, err := zip.NewReader(f, info.Size())
+	require.NoError(t, err)
+	require.Len(t, r.File, 2)
+	require.Equal(t, "logs/", r.File[0].Name)
+	require.True(t, r.File[0].FileInfo().IsDir())
+	require.Equal(t, "foo.txt", r.File[1].Name)
+}
+
+func TestZipAddWithReader(t *testing.T) {
+	for _, reproducible := range []bool{false, true} {
+		var opts []Option
+		if reproducible {
+			opts = append(opts, WithReproducible())
+		}
+		var buf bytes.Buffer
+		archive := New(&buf, opts...)
+		require.NoError(t, addMapFile(t, archive, "app/bin/app", &fstest.MapFile{Data: []byte("app"), Mode: 0o755}))
+		require.NoError(t, addMapFile(t, archive, "app/etc/app.yml", &fstest.MapFile{Data: []byte("a: b"), Mode: 0o640}))
+		require.ErrorIs(t, addMapFile(t, archive, "app/bin/app", &fstest.MapFile{}), fs.ErrExist)
+		require.NoError(t, archive.Close())
+
+		r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		modes := map[string]fs.FileMode{}
+		for _, zf := range r.File {
+			modes[zf.Name] = zf.Mode()
+			rc, err := zf.Open()
+			require.NoError(t, err)
+			bts, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+			require.NotEmpty(t, bts)
+		}
+		require.Equal(t, map[string]fs.FileMode{
+			"app/bin/app":     0o755,
+			"app/etc/app.yml": 0o640,
+		}, modes)
+	}
+}
+
+// addMapFile adds the given file at the given destination with
+// AddWithReader.
+func addMapFile(tb testing.TB, a Archive, dst string, file *fstest.MapFile) error {
+	tb.Helper()
+	info, err := fs.Stat(fstest.MapFS{"file": file}, "file")
+	require.NoError(tb, err)
+	return a.AddWithReader(config.File{Destination: dst}, info, bytes.NewReader(file.Data))
+}
+
+func TestZipModifiedAndDOSAttributes(t *testing.T) {
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	var buf bytes.Buffer
+	archive := New(&buf)
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "readonly.txt",
+		Info: config.FileInfo{
+			Mode:        0o444,
+			ParsedMTime: mtime,
+		},
+	}))
+	require.NoError(t, archive.Add(config.File{
+		Source:      "../testdata/foo.txt",
+		Destination: "writable.txt",
+		Info: config.FileInfo{
+			Mode: 0o644,
+		},
+	}))
+	require.NoError(t, archive.Close())
+
+	stat, err := os.Stat("../testdata/foo.txt")
+	require.NoError(t, err)
+
+	const msdosReadOnly = 0x01
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, r.File, 2)
+
+	require.Equal(t, "readonly.txt", r.File[0].Name)
+	require.WithinDuration(t, mtime, r.File[0].Modified, time.Second)
+	require.Equal(t, uint32(msdosReadOnly), r.File[0].ExternalAttrs&msdosReadOnly)
+
+	require.Equal(t, "writable.txt", r.File[1].Name)
+	require.WithinDuration(t, stat.ModTime(), r.File[1].Modified, time.Second)
+	require.Zero(t, r.File[1].ExternalAttrs&msdosReadOnly)
+}
+
+func TestZipReproducible(t *testing.T) {
+	files := []config.File{
+		{Source: "../testdata/foo.txt", Destination: "foo.txt"},
+		{Source: "../testdata/sub1/bar.txt", Destination: "sub1/bar.txt"},
+		{Source: "../testdata/sub1/executable", Destination: "sub1/executable"},
+		{Destination: "logs", Info: config.FileInfo{Mode: os.ModeDir | 0o755, ParsedMTime: time.Unix(0, 0)}},
+		{Source: "../testdata/regular.txt", Destination: "a.txt", Info: config.FileInfo{
+			ParsedMTime: time.Date(2020, 1, 2, 3, 4, 5, 6, time.FixedZone("x", 3600)),
+		}},
+	}
+	embedded := &fstest.MapFile{Data: []byte("embedded"), Mode: 0o644, ModTime: time.Unix(1000, 0)}
+
+	build := func(tb testing.TB, files []config.File) []byte {
+		tb.Helper()
+		var buf bytes.Buffer
+		archive := New(&buf, WithReproducible())
+		for _, f := range files {
+			require.NoError(tb, archive.Add(f))
+		}
+		require.NoError(tb, addMapFile(tb, archive, "fs/embedded.txt", embedded))
+		require.Error(tb, archive.Add(config.File{
+			Source:      "../testdata/nope.txt",
+			Destination: "nope.txt",
+		}))
+		require.NoError(tb, archive.Close())
+		return buf.Bytes()
+	}
+
+	first := build(t, files)
+	reversed := slices.Clone(files)
+	slices.Reverse(reversed)
+	require.Equal(t, first, build(t, reversed))
+
+	r, err := zip.NewReader(bytes.NewReader(first), int64(len(first)))
+	require.NoError(t, err)
+	var names []string
+	for _, zf := range r.File {
+		names = append(names, zf.Name)
+	}
+	require.Equal(t, []string{
+		"a.txt",
+		"foo.txt",
+		"fs/embedded.txt",
+		"logs/",
+		"sub1/bar.txt",
+		"sub1/executable",
+	}, names)
+}
+
+func TestZipCopyBufferSize(t *testing.T) {
+	content := make([]byte, 100_000)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(content)
+	src := filepath.Join(t.TempDir(), "random.bin")
+	require.NoError(t, os.WriteFile(src, content, 0o644))
+
+	for _, size := range []int{7, 1 << 20} {
+		var buf bytes.Buffer
+		archive := New(&buf, WithCopyBufferSize(size))
+		require.NoError(t, archive.Add(config.File{
+			Source:      src,
+			Destination: "random.bin",
+		}))
+		require.NoError(t, addMapFile(t, archive, "fs/random.bin", &fstest.MapFile{Data: content, Mode: 0o644}))
+		require.NoError(t, archive.Close())
+
+		r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		require.Len(t, r.File, 2)
+		for _, zf := range r.File {
+			rc, err := zf.Open()
+			require.NoError(t, err)
+			bts, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+			require.Equal(t, content, bts, zf.Name)
+		}
+	}
+}
+
+func TestZipComments(t *testing.T) {
+	for _, reproducible := range []bool{false, true} {
+		opts := []Option{WithComment("signed by the release team")}
+		if reproducible {
+			opts = append(opts, WithReproducible())
+		}
+		var buf bytes.Buffer
+		archive := New(&buf, opts...)
+		require.NoError(t, archive.Add(config.File{
+			Source:      "../testdata/foo.txt",
+			Destination: "foo.txt",
+			Zip:         config.ZipFileInfo{Comment: "generated"},
+		}))
+		require.NoError(t, archive.Add(config.File{
+			Destination: "dir",
+			Info:        config.FileInfo{Mode: fs.ModeDir | 0o755},
+			Zip:         config.ZipFileInfo{Comment: "empty"},
+		}))
+		require.NoError(t, archive.Add(config.File{
+			Source:      "../testdata/sub1/bar.txt",
+			Destination: "bar.txt",
+		}))
+		require.NoError(t, archive.Close())
+
+		r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		require.Equal(t, "signed by the release team", r.Comment)
+		comments := map[string]string{}
+		for _, zf := range r.File {
+			comments[zf.Name] = zf.Comment
+		}
+		require.Equal(t, map[string]string{
+			"foo.txt": "generated",
+			"dir/":    "empty",
+			"bar.txt": "",
+		}, comments)
+	}
+}
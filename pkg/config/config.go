@@ -551,11 +551,18 @@ type FormatOverride struct {
 
 // File is a file inside an archive.
 type File struct {
-	Source      string   `yaml:"src,omitempty" json:"src,omitempty"`
-	Destination string   `yaml:"dst,omitempty" json:"dst,omitempty"`
-	StripParent bool     `yaml:"strip_parent,omitempty" json:"strip_parent,omitempty"`
-	Info        FileInfo `yaml:"info,omitempty" json:"info,omitempty"`
-	Default     bool     `yaml:"-" json:"-"`
+	Source      string      `yaml:"src,omitempty" json:"src,omitempty"`
+	Destination string      `yaml:"dst,omitempty" json:"dst,omitempty"`
+	StripParent bool        `yaml:"strip_parent,omitempty" json:"strip_parent,omitempty"`
+	Info        FileInfo    `yaml:"info,omitempty" json:"info,omitempty"`
+	Zip         ZipFileInfo `yaml:"zip,omitempty" json:"zip,omitempty"`
+	Default     bool        `yaml:"-" json:"-"`
+}
+
+// ZipFileInfo is the file info of a file which is only used by zip archives.
+type ZipFileInfo struct {
+	CompressionMethod string `yaml:"compression_method,omitempty" json:"compression_method,omitempty" jsonschema:"enum=store,enum=deflate"`
+	Comment           string `yaml:"comment,omitempty" json:"comment,omitempty"`
 }
 
 // FileInfo is the file info of a file.
@@ -565,6 +572,11 @@ type FileInfo struct {
 	Mode        os.FileMode `yaml:"mode,omitempty" json:"mode,omitempty"`
 	MTime       string      `yaml:"mtime,omitempty" json:"mtime,omitempty"`
 	ParsedMTime time.Time   `yaml:"-" json:"-"`
+
+	// DevMajor and DevMinor are the device numbers of entries declared as
+	// char or block devices through their mode, without a source.
+	DevMajor int64 `yaml:"-" json:"-"`
+	DevMinor int64 `yaml:"-" json:"-"`
 }
 
 // UniversalBinary setups macos universal binaries.